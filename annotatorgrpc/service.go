@@ -0,0 +1,260 @@
+// Package annotatorgrpc serves a GNAnnotator over gRPC, as described in
+// annotator.proto.
+package annotatorgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	gn "github.com/genome-nexus/genome-nexus-go"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	AnnotateFullMethod       = "/genomenexus.annotator.v1.Annotator/Annotate"
+	AnnotateStreamFullMethod = "/genomenexus.annotator.v1.Annotator/AnnotateStream"
+
+	// IsoformOverrideSourceKey is the request metadata overriding the server's
+	// isoform override source.
+	IsoformOverrideSourceKey = "isoform-override-source"
+	// ReportKey is the trailer of Annotate holding the JSON AnnotationReport
+	// of the annotated message. AnnotateStream does not send it, since the
+	// reports of a long stream would outgrow the metadata size limit.
+	ReportKey = "annotation-report-bin"
+	// StatusesKey is the trailer of AnnotateStream holding the JSON status
+	// counts of every message, keyed by the index of the message in the stream.
+	StatusesKey          = "annotation-statuses-bin"
+	SucceededKey         = "annotation-succeeded"
+	FailedKey            = "annotation-failed"
+	AnnotationVersionKey = "annotation-version"
+)
+
+// AnnotatorServer is the server API of the Annotator service.
+type AnnotatorServer interface {
+	Annotate(ctx context.Context, tm *tt.TempoMessage) (*tt.TempoMessage, error)
+	AnnotateStream(stream grpc.BidiStreamingServer[tt.TempoMessage, tt.TempoMessage]) error
+}
+
+// ServiceDesc is the grpc.ServiceDesc of the Annotator service.
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "genomenexus.annotator.v1.Annotator",
+	HandlerType: (*AnnotatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Annotate", Handler: annotateHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "AnnotateStream", Handler: annotateStreamHandler, ServerStreams: true, ClientStreams: true},
+	},
+	Metadata: "annotator.proto",
+}
+
+// RegisterAnnotatorServer registers srv with s.
+func RegisterAnnotatorServer(s grpc.ServiceRegistrar, srv AnnotatorServer) {
+	s.RegisterService(&ServiceDesc, srv)
+}
+
+func annotateHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(tt.TempoMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnnotatorServer).Annotate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: AnnotateFullMethod}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnnotatorServer).Annotate(ctx, req.(*tt.TempoMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func annotateStreamHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AnnotatorServer).AnnotateStream(&grpc.GenericServerStream[tt.TempoMessage, tt.TempoMessage]{ServerStream: stream})
+}
+
+// Server implements AnnotatorServer with a GNAnnotator.
+type Server struct {
+	annotator             gn.GNAnnotator
+	isoformOverrideSource string
+}
+
+// NewServer returns a Server annotating with annotator, using
+// isoformOverrideSource unless a request sets its own.
+func NewServer(annotator gn.GNAnnotator, isoformOverrideSource string) *Server {
+	return &Server{annotator: annotator, isoformOverrideSource: isoformOverrideSource}
+}
+
+// isoform returns the isoform override source requested in the metadata of ctx.
+func (s *Server) isoform(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(IsoformOverrideSourceKey); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	return s.isoformOverrideSource
+}
+
+// Annotate annotates tm within the deadline of ctx. It fails with Unavailable
+// when none of the events could be sent to Genome Nexus.
+func (s *Server) Annotate(ctx context.Context, tm *tt.TempoMessage) (*tt.TempoMessage, error) {
+	report, err := s.annotator.AnnotateTempoMessageEventsContext(ctx, s.isoform(ctx), tm)
+	if ctx.Err() != nil {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if err != nil && report.Succeeded() == 0 && len(tm.Events) > 0 {
+		return nil, status.Errorf(codes.Unavailable, "Genome Nexus request failed: %v", err)
+	}
+	trailer, err := reportTrailer(report)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode the annotation report: %v", err)
+	}
+	grpc.SetTrailer(ctx, trailer)
+	return tm, nil
+}
+
+// AnnotateStream annotates the messages received on stream, sending each one
+// back once annotated. Messages arriving close together are annotated
+// together, so locations they share are only requested once. The trailer
+// holds the numbers of annotated and failed events of the whole stream and
+// the status counts of every message.
+func (s *Server) AnnotateStream(stream grpc.BidiStreamingServer[tt.TempoMessage, tt.TempoMessage]) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	in := make(chan *tt.TempoMessage)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(in)
+		for {
+			tm, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					recvErr <- err
+					cancel()
+				}
+				return
+			}
+			select {
+			case in <- tm:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	succeeded, failed, version := 0, 0, ""
+	statuses := make(map[int]map[gn.AnnotationStatusCode]int)
+	for res := range s.annotator.AnnotateStream(ctx, s.isoform(ctx), in) {
+		if err := stream.Send(res.Message); err != nil {
+			return err
+		}
+		statuses[len(statuses)] = res.Report.StatusCounts
+		succeeded += res.Report.Succeeded()
+		failed += res.Report.Failed()
+		if version == "" {
+			version = res.Report.AnnotationVersion()
+		}
+	}
+	select {
+	case err := <-recvErr:
+		return err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	trailer := countsTrailer(succeeded, failed, version)
+	b, err := json.Marshal(statuses)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to encode the annotation statuses: %v", err)
+	}
+	trailer.Set(StatusesKey, string(b))
+	stream.SetTrailer(trailer)
+	return nil
+}
+
+// reportTrailer encodes report as response metadata.
+func reportTrailer(report *gn.AnnotationReport) (metadata.MD, error) {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	md := countsTrailer(report.Succeeded(), report.Failed(), report.AnnotationVersion())
+	md.Set(ReportKey, string(b))
+	return md, nil
+}
+
+// countsTrailer encodes the numbers of annotated and failed events as response
+// metadata.
+func countsTrailer(succeeded, failed int, version string) metadata.MD {
+	md := metadata.MD{}
+	md.Set(SucceededKey, strconv.Itoa(succeeded))
+	md.Set(FailedKey, strconv.Itoa(failed))
+	if version != "" {
+		md.Set(AnnotationVersionKey, version)
+	}
+	return md
+}
+
+// ReportFromTrailer decodes the annotation report sent in the trailer of
+// Annotate.
+func ReportFromTrailer(md metadata.MD) (*gn.AnnotationReport, error) {
+	values := md.Get(ReportKey)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no %s trailer", ReportKey)
+	}
+	report := &gn.AnnotationReport{}
+	if err := json.Unmarshal([]byte(values[0]), report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// StatusesFromTrailer decodes the status counts of every message sent in the
+// trailer of AnnotateStream, keyed by the index of the message in the stream.
+func StatusesFromTrailer(md metadata.MD) (map[int]map[gn.AnnotationStatusCode]int, error) {
+	values := md.Get(StatusesKey)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no %s trailer", StatusesKey)
+	}
+	var statuses map[int]map[gn.AnnotationStatusCode]int
+	if err := json.Unmarshal([]byte(values[0]), &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// AnnotatorClient is a client of the Annotator service.
+type AnnotatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAnnotatorClient(cc grpc.ClientConnInterface) *AnnotatorClient {
+	return &AnnotatorClient{cc: cc}
+}
+
+// Annotate annotates tm. Pass grpc.Trailer to receive the annotation report.
+func (c *AnnotatorClient) Annotate(ctx context.Context, tm *tt.TempoMessage, opts ...grpc.CallOption) (*tt.TempoMessage, error) {
+	out := new(tt.TempoMessage)
+	if err := c.cc.Invoke(ctx, AnnotateFullMethod, tm, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AnnotateStream opens a stream annotating the messages sent on it.
+func (c *AnnotatorClient) AnnotateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[tt.TempoMessage, tt.TempoMessage], error) {
+	stream, err := c.cc.NewStream(ctx, &ServiceDesc.Streams[0], AnnotateStreamFullMethod, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpc.GenericClientStream[tt.TempoMessage, tt.TempoMessage]{ClientStream: stream}, nil
+}
@@ -0,0 +1,133 @@
+package annotatorgrpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	gn "github.com/genome-nexus/genome-nexus-go"
+	"github.com/genome-nexus/genome-nexus-go/internal/gntest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, annotator gn.GNAnnotator) *AnnotatorClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterAnnotatorServer(s, NewServer(annotator, "mskcc"))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewAnnotatorClient(conn)
+}
+
+func TestAnnotate(t *testing.T) {
+	annotator := &gntest.Annotator{}
+	client := newTestClient(t, annotator)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), IsoformOverrideSourceKey, "uniprot")
+	var trailer metadata.MD
+	tm, err := client.Annotate(ctx, gntest.NewTestMessage("s1"), grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if tm.Events[0].HugoSymbol != "s1" {
+		t.Errorf("expected the annotated message but got %v", tm)
+	}
+	if isoforms := annotator.Isoforms(); len(isoforms) != 1 || isoforms[0] != "uniprot" {
+		t.Errorf("expected the isoform override source from the metadata but got %v", isoforms)
+	}
+	report, err := ReportFromTrailer(trailer)
+	if err != nil || report.Succeeded() != 1 {
+		t.Errorf("unexpected report %v (%v)", report, err)
+	}
+	if v := trailer.Get(SucceededKey); len(v) != 1 || v[0] != "1" {
+		t.Errorf("unexpected %s trailer %v", SucceededKey, v)
+	}
+}
+
+func TestAnnotateDeadline(t *testing.T) {
+	client := newTestClient(t, &gntest.Annotator{Delay: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Annotate(ctx, gntest.NewTestMessage("s1"))
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded but got %v", err)
+	}
+}
+
+func TestAnnotateStream(t *testing.T) {
+	client := newTestClient(t, &gntest.Annotator{})
+
+	stream, err := client.AnnotateStream(context.Background())
+	if err != nil {
+		t.Fatalf("AnnotateStream failed: %v", err)
+	}
+	samples := []string{"s1", "s2", "s3"}
+	for _, s := range samples {
+		if err := stream.Send(gntest.NewTestMessage(s)); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	stream.CloseSend()
+	for _, s := range samples {
+		tm, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if tm.CmoSampleId != s || tm.Events[0].HugoSymbol != s {
+			t.Errorf("expected %s to be annotated in order but got %v", s, tm)
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected the stream to end but got %v", err)
+	}
+	trailer := stream.Trailer()
+	if v := trailer.Get(SucceededKey); len(v) != 1 || v[0] != "3" {
+		t.Errorf("unexpected %s trailer %v", SucceededKey, v)
+	}
+	if v := trailer.Get(ReportKey); len(v) != 0 {
+		t.Errorf("expected no reports in the stream trailer but got %v", v)
+	}
+	statuses, err := StatusesFromTrailer(trailer)
+	if err != nil || len(statuses) != len(samples) {
+		t.Fatalf("unexpected statuses %v (%v)", statuses, err)
+	}
+	for i := range samples {
+		if statuses[i][gn.StatusSuccess] != 1 {
+			t.Errorf("expected message %d to have 1 annotated event but got %v", i, statuses[i])
+		}
+	}
+}
+
+func TestAnnotateStreamCancel(t *testing.T) {
+	client := newTestClient(t, &gntest.Annotator{Delay: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.AnnotateStream(ctx)
+	if err != nil {
+		t.Fatalf("AnnotateStream failed: %v", err)
+	}
+	if err := stream.Send(gntest.NewTestMessage("s1")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("expected Canceled but got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package genome_nexus_annotator_go
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+// AnnotationCache stores Genome Nexus variant annotations so repeated genomic
+// locations are not requested again. Implementations must be safe for
+// concurrent use.
+type AnnotationCache interface {
+	Get(key string) (gnapi.VariantAnnotation, bool)
+	// Put stores va under key. Caching is best effort: a failed Put only means
+	// the location is requested again later.
+	Put(key string, va gnapi.VariantAnnotation)
+}
+
+// annotationCacheKey identifies a cached annotation of a genomic location. It includes
+// everything that changes the Genome Nexus response so upgrades and different
+// settings never share entries.
+func annotationCacheKey(p Provenance, genomicLocationKey string) string {
+	return strings.Join([]string{
+		p.AnnotationVersion(),
+		p.IsoformOverrideSource,
+		strings.Join(p.EnrichmentFields, ","),
+		genomicLocationKey,
+	}, "|")
+}
+
+// MemoryCache is an AnnotationCache held in memory. It never evicts entries;
+// long-running processes should use an LRUCache instead.
+type MemoryCache struct {
+	mu          sync.RWMutex
+	annotations map[string]gnapi.VariantAnnotation
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{annotations: make(map[string]gnapi.VariantAnnotation)}
+}
+
+func (c *MemoryCache) Get(key string) (gnapi.VariantAnnotation, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	va, ok := c.annotations[key]
+	return va, ok
+}
+
+func (c *MemoryCache) Put(key string, va gnapi.VariantAnnotation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.annotations[key] = va
+}
+
+// LRUCache is an AnnotationCache held in memory that keeps at most a fixed
+// number of annotations, evicting the least recently used ones.
+type LRUCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key string
+	va  gnapi.VariantAnnotation
+}
+
+// NewLRUCache returns an LRUCache keeping at most maxEntries annotations.
+func NewLRUCache(maxEntries int) *LRUCache {
+	return &LRUCache{
+		maxEntries: max(maxEntries, 1),
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) (gnapi.VariantAnnotation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return gnapi.VariantAnnotation{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).va, true
+}
+
+func (c *LRUCache) Put(key string, va gnapi.VariantAnnotation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*lruEntry).va = va
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, va: va})
+	if c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of cached annotations.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// FileCache is an AnnotationCache storing one JSON file per annotation in a
+// directory, so it can be shared between runs.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache returns a FileCache in dir, creating the directory if needed.
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create annotation cache directory %q: %v", dir, err)
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *FileCache) Get(key string) (gnapi.VariantAnnotation, bool) {
+	var va gnapi.VariantAnnotation
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return va, false
+	}
+	if err := json.Unmarshal(b, &va); err != nil {
+		return va, false
+	}
+	return va, true
+}
+
+func (c *FileCache) Put(key string, va gnapi.VariantAnnotation) {
+	b, err := json.Marshal(va)
+	if err != nil {
+		return
+	}
+	// write to a temporary file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,28 @@
+package genome_nexus_annotator_go
+
+import (
+	"testing"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Put("a", gnapi.VariantAnnotation{Variant: "a"})
+	c.Put("b", gnapi.VariantAnnotation{Variant: "b"})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	c.Put("c", gnapi.VariantAnnotation{Variant: "c"})
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if va, ok := c.Get(key); !ok || va.Variant != key {
+			t.Errorf("expected %s to be cached but got %+v", key, va)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries but got %d", c.Len())
+	}
+}
@@ -0,0 +1,108 @@
+package genome_nexus_annotator_go
+
+import (
+	"strconv"
+	"strings"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+// ClinvarEnrichment is the ClinVar record of a variant.
+//
+// Genome Nexus builds its clinvar annotation from the ClinVar VCF and only
+// returns the variation id and the CLNSIG and CLNSIGCONF values. Review status
+// and conditions are not part of the Genome Nexus response, so they cannot be
+// resolved here.
+type ClinvarEnrichment struct {
+	// ClinvarId is the ClinVar variation id.
+	ClinvarId int `json:"clinvarId,omitempty"`
+	// ClinicalSignificance is the aggregate classification, e.g.
+	// "Pathogenic/Likely_pathogenic".
+	ClinicalSignificance string `json:"clinicalSignificance,omitempty"`
+	// Conflicting is true when submitters disagree on the classification.
+	Conflicting bool `json:"conflicting"`
+	// ConflictingInterpretations lists the classifications of the submitters
+	// when they conflict.
+	ConflictingInterpretations []ClinvarInterpretation `json:"conflictingInterpretations,omitempty"`
+}
+
+// ClinvarInterpretation is one of the conflicting classifications of a variant.
+type ClinvarInterpretation struct {
+	ClinicalSignificance string `json:"clinicalSignificance"`
+	Submissions          int    `json:"submissions"`
+}
+
+// IsPathogenic reports whether ClinVar classifies the variant as pathogenic or
+// likely pathogenic without conflicting interpretations.
+func (c *ClinvarEnrichment) IsPathogenic() bool {
+	if c == nil || c.Conflicting {
+		return false
+	}
+	for _, s := range strings.FieldsFunc(strings.ToLower(c.ClinicalSignificance), isClinsigSeparator) {
+		if s == "pathogenic" || s == "likely_pathogenic" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBenign reports whether ClinVar classifies the variant as benign or likely
+// benign without conflicting interpretations.
+func (c *ClinvarEnrichment) IsBenign() bool {
+	if c == nil || c.Conflicting {
+		return false
+	}
+	for _, s := range strings.FieldsFunc(strings.ToLower(c.ClinicalSignificance), isClinsigSeparator) {
+		if s == "benign" || s == "likely_benign" {
+			return true
+		}
+	}
+	return false
+}
+
+func isClinsigSeparator(r rune) bool {
+	return r == '/' || r == ',' || r == '|'
+}
+
+// resolveClinvar returns the ClinVar record of the variant, or nil when the
+// variant is not in ClinVar.
+func resolveClinvar(gnResponse gnapi.VariantAnnotation) *ClinvarEnrichment {
+	if gnResponse.Clinvar == nil || gnResponse.Clinvar.Annotation == nil {
+		return nil
+	}
+	clinvar := gnResponse.Clinvar.Annotation
+	enrichment := &ClinvarEnrichment{}
+	if clinvar.ClinvarId != nil {
+		enrichment.ClinvarId = int(*clinvar.ClinvarId)
+	}
+	if clinvar.ClinicalSignificance != nil {
+		enrichment.ClinicalSignificance = *clinvar.ClinicalSignificance
+	}
+	if clinvar.ConflictingClinicalSignificance != nil {
+		enrichment.ConflictingInterpretations = parseConflictingClinicalSignificance(*clinvar.ConflictingClinicalSignificance)
+	}
+	enrichment.Conflicting = len(enrichment.ConflictingInterpretations) > 0 ||
+		strings.HasPrefix(strings.ToLower(enrichment.ClinicalSignificance), "conflicting")
+	return enrichment
+}
+
+// parseConflictingClinicalSignificance parses a CLNSIGCONF value such as
+// "Pathogenic(1)|Uncertain_significance(2)".
+func parseConflictingClinicalSignificance(s string) []ClinvarInterpretation {
+	var interpretations []ClinvarInterpretation
+	for _, part := range strings.Split(s, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		interpretation := ClinvarInterpretation{ClinicalSignificance: part}
+		if open := strings.LastIndex(part, "("); open > 0 && strings.HasSuffix(part, ")") {
+			if n, err := strconv.Atoi(part[open+1 : len(part)-1]); err == nil {
+				interpretation.ClinicalSignificance = part[:open]
+				interpretation.Submissions = n
+			}
+		}
+		interpretations = append(interpretations, interpretation)
+	}
+	return interpretations
+}
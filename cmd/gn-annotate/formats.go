@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// document is an input file read into TempoMessages. Once the messages are
+// annotated in place, write writes the document back in its own format.
+type document interface {
+	messages() []*tt.TempoMessage
+	write(w io.Writer) error
+}
+
+// formats maps each -format value to the reader of that format.
+var formats = map[string]func(r io.Reader, cfg config) (document, error){
+	"json":  readJSON,
+	"proto": readProto,
+	"maf":   readMAF,
+	"vcf":   readVCF,
+}
+
+// formatFromPath guesses the format of path from its extension.
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".pb", ".bin", ".protobuf":
+		return "proto"
+	case ".maf":
+		return "maf"
+	case ".vcf":
+		return "vcf"
+	}
+	return ""
+}
+
+// jsonDocument is TempoMessage JSON in the {"records": [...]} shape of
+// testdata/tempo_message.annotated.json.
+type jsonDocument struct {
+	Records []*tt.TempoMessage `json:"records"`
+}
+
+func readJSON(r io.Reader, _ config) (document, error) {
+	var doc jsonDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (d *jsonDocument) messages() []*tt.TempoMessage {
+	return d.Records
+}
+
+func (d *jsonDocument) write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// protoDocument is a stream of size-delimited binary TempoMessages.
+type protoDocument struct {
+	records []*tt.TempoMessage
+}
+
+func readProto(r io.Reader, _ config) (document, error) {
+	doc := &protoDocument{}
+	br := bufio.NewReader(r)
+	for {
+		tm := &tt.TempoMessage{}
+		err := protodelim.UnmarshalFrom(br, tm)
+		if errors.Is(err, io.EOF) {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc.records = append(doc.records, tm)
+	}
+}
+
+func (d *protoDocument) messages() []*tt.TempoMessage {
+	return d.records
+}
+
+func (d *protoDocument) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, tm := range d.records {
+		if _, err := protodelim.MarshalTo(bw, tm); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
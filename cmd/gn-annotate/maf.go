@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// mafColumn is a MAF column read into and written from an Event field.
+type mafColumn struct {
+	name  string
+	field func(e *tt.Event) *string
+}
+
+// mafColumns lists the MAF columns backed by Event fields, in the order they
+// are added to the output when missing from the input.
+var mafColumns = []mafColumn{
+	{"Hugo_Symbol", func(e *tt.Event) *string { return &e.HugoSymbol }},
+	{"Entrez_Gene_Id", func(e *tt.Event) *string { return &e.EntrezGeneId }},
+	{"Center", func(e *tt.Event) *string { return &e.Center }},
+	{"NCBI_Build", func(e *tt.Event) *string { return &e.NcbiBuild }},
+	{"Chromosome", func(e *tt.Event) *string { return &e.Chromosome }},
+	{"Start_Position", func(e *tt.Event) *string { return &e.StartPosition }},
+	{"End_Position", func(e *tt.Event) *string { return &e.EndPosition }},
+	{"Strand", func(e *tt.Event) *string { return &e.Strand }},
+	{"Variant_Classification", func(e *tt.Event) *string { return &e.VariantClassification }},
+	{"Variant_Type", func(e *tt.Event) *string { return &e.VariantType }},
+	{"Reference_Allele", func(e *tt.Event) *string { return &e.ReferenceAllele }},
+	{"Tumor_Seq_Allele1", func(e *tt.Event) *string { return &e.TumorSeqAllele1 }},
+	{"Tumor_Seq_Allele2", func(e *tt.Event) *string { return &e.TumorSeqAllele2 }},
+	{"dbSNP_RS", func(e *tt.Event) *string { return &e.DbsnpRs }},
+	{"dbSNP_Val_Status", func(e *tt.Event) *string { return &e.DbsnpValStatus }},
+	{"Tumor_Sample_Barcode", func(e *tt.Event) *string { return &e.TumorSampleBarcode }},
+	{"Matched_Norm_Sample_Barcode", func(e *tt.Event) *string { return &e.MatchedNormSampleBarcode }},
+	{"Match_Norm_Seq_Allele1", func(e *tt.Event) *string { return &e.MatchNormSeqAllele1 }},
+	{"Match_Norm_Seq_Allele2", func(e *tt.Event) *string { return &e.MatchNormSeqAllele2 }},
+	{"Tumor_Validation_Allele1", func(e *tt.Event) *string { return &e.TumorValidationAllele1 }},
+	{"Tumor_Validation_Allele2", func(e *tt.Event) *string { return &e.TumorValidationAllele2 }},
+	{"Match_Norm_Validation_Allele1", func(e *tt.Event) *string { return &e.MatchNormValidationAllele1 }},
+	{"Match_Norm_Validation_Allele2", func(e *tt.Event) *string { return &e.MatchNormValidationAllele2 }},
+	{"Verification_Status", func(e *tt.Event) *string { return &e.VerificationStatus }},
+	{"Validation_Status", func(e *tt.Event) *string { return &e.ValidationStatus }},
+	{"Mutation_Status", func(e *tt.Event) *string { return &e.MutationStatus }},
+	{"Sequencing_Phase", func(e *tt.Event) *string { return &e.SequencingPhase }},
+	{"Sequence_Source", func(e *tt.Event) *string { return &e.SequencingSource }},
+	{"Validation_Method", func(e *tt.Event) *string { return &e.ValidationMethod }},
+	{"Score", func(e *tt.Event) *string { return &e.Score }},
+	{"BAM_File", func(e *tt.Event) *string { return &e.BamFile }},
+	{"Sequencer", func(e *tt.Event) *string { return &e.Sequencer }},
+	{"t_ref_count", func(e *tt.Event) *string { return &e.TRefCount }},
+	{"t_alt_count", func(e *tt.Event) *string { return &e.TAltCount }},
+	{"n_ref_count", func(e *tt.Event) *string { return &e.NRefCount }},
+	{"n_alt_count", func(e *tt.Event) *string { return &e.NAltCount }},
+	{"HGVSc", func(e *tt.Event) *string { return &e.Hgvsc }},
+	{"HGVSp", func(e *tt.Event) *string { return &e.Hgvsp }},
+	{"HGVSp_Short", func(e *tt.Event) *string { return &e.HgvspShort }},
+	{"Transcript_ID", func(e *tt.Event) *string { return &e.TranscriptId }},
+	{"RefSeq", func(e *tt.Event) *string { return &e.Refseq }},
+	{"Protein_position", func(e *tt.Event) *string { return &e.ProteinPosition }},
+	{"Codons", func(e *tt.Event) *string { return &e.Codons }},
+	{"Exon_Number", func(e *tt.Event) *string { return &e.ExonNumber }},
+	{"Consequence", func(e *tt.Event) *string { return &e.Consequence }},
+	{"PolyPhen_Prediction", func(e *tt.Event) *string { return &e.PolyphenPrediction }},
+	{"PolyPhen_Score", func(e *tt.Event) *string { return &e.PolyphenScore }},
+	{"SIFT_Prediction", func(e *tt.Event) *string { return &e.SiftPrediction }},
+	{"SIFT_Score", func(e *tt.Event) *string { return &e.SiftScore }},
+	{"gnomAD_AF", func(e *tt.Event) *string { return &e.GnomadAf }},
+	{"gnomAD_AFR_AF", func(e *tt.Event) *string { return &e.GnomadAfrAf }},
+	{"gnomAD_AMR_AF", func(e *tt.Event) *string { return &e.GnomadAmrAf }},
+	{"gnomAD_ASJ_AF", func(e *tt.Event) *string { return &e.GnomadAsjAf }},
+	{"gnomAD_EAS_AF", func(e *tt.Event) *string { return &e.GnomadEasAf }},
+	{"gnomAD_FIN_AF", func(e *tt.Event) *string { return &e.GnomadFinAf }},
+	{"gnomAD_NFE_AF", func(e *tt.Event) *string { return &e.GnomadNfeAf }},
+	{"gnomAD_OTH_AF", func(e *tt.Event) *string { return &e.GnomadOthAf }},
+	{"gnomAD_SAS_AF", func(e *tt.Event) *string { return &e.GnomadSasAf }},
+	{"MA:FImpact", func(e *tt.Event) *string { return &e.MaFunctionalImpact }},
+	{"MA:FIS", func(e *tt.Event) *string { return &e.MaFunctionalImpactScore }},
+	{"MA:link.MSA", func(e *tt.Event) *string { return &e.MaLinkMsa }},
+	{"MA:link.PDB", func(e *tt.Event) *string { return &e.MaLinkPdb }},
+	{"Genomic_Location_Explanation", func(e *tt.Event) *string { return &e.GenomicLocationExplanation }},
+	{"Annotation_Status", func(e *tt.Event) *string { return &e.AnnotationStatus }},
+}
+
+// mafDocument is a MAF file. Columns that are not backed by Event fields are
+// written back unchanged.
+type mafDocument struct {
+	comments []string
+	header   []string
+	rows     []mafRow
+	records  []*tt.TempoMessage
+}
+
+type mafRow struct {
+	values []string
+	event  *tt.Event
+}
+
+// readMAF reads a MAF file, grouping its rows into one TempoMessage per
+// Tumor_Sample_Barcode in the order the samples first appear.
+func readMAF(r io.Reader, _ config) (document, error) {
+	doc := &mafDocument{}
+	columns := make(map[int]mafColumn)
+	bySample := make(map[string]*tt.TempoMessage)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if strings.HasPrefix(text, "#") {
+			doc.comments = append(doc.comments, text)
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		values := strings.Split(text, "\t")
+		if doc.header == nil {
+			doc.header = values
+			for i, name := range values {
+				for _, c := range mafColumns {
+					if strings.EqualFold(strings.TrimSpace(name), c.name) {
+						columns[i] = c
+					}
+				}
+			}
+			continue
+		}
+		if len(values) > len(doc.header) {
+			return nil, fmt.Errorf("line %d: %d values for %d columns", line, len(values), len(doc.header))
+		}
+		for len(values) < len(doc.header) {
+			values = append(values, "")
+		}
+
+		e := &tt.Event{}
+		for i, c := range columns {
+			*c.field(e) = values[i]
+		}
+		tm, ok := bySample[e.TumorSampleBarcode]
+		if !ok {
+			tm = &tt.TempoMessage{CmoSampleId: e.TumorSampleBarcode}
+			bySample[e.TumorSampleBarcode] = tm
+			doc.records = append(doc.records, tm)
+		}
+		tm.Events = append(tm.Events, e)
+		doc.rows = append(doc.rows, mafRow{values: values, event: e})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if doc.header == nil {
+		return nil, fmt.Errorf("missing MAF header")
+	}
+	return doc, nil
+}
+
+func (d *mafDocument) messages() []*tt.TempoMessage {
+	return d.records
+}
+
+// write writes the rows in their input order, adding the Event backed columns
+// missing from the input after the input columns.
+func (d *mafDocument) write(w io.Writer) error {
+	header := append([]string{}, d.header...)
+	fields := make([]func(e *tt.Event) *string, len(header))
+	present := make(map[string]bool)
+	for i, name := range header {
+		for _, c := range mafColumns {
+			if strings.EqualFold(strings.TrimSpace(name), c.name) {
+				fields[i] = c.field
+				present[c.name] = true
+			}
+		}
+	}
+	for _, c := range mafColumns {
+		if !present[c.name] {
+			header = append(header, c.name)
+			fields = append(fields, c.field)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, c := range d.comments {
+		fmt.Fprintln(bw, c)
+	}
+	fmt.Fprintln(bw, strings.Join(header, "\t"))
+	values := make([]string, len(header))
+	for _, row := range d.rows {
+		for i := range header {
+			switch {
+			case fields[i] != nil:
+				values[i] = *fields[i](row.event)
+			case i < len(row.values):
+				values[i] = row.values[i]
+			default:
+				values[i] = ""
+			}
+		}
+		fmt.Fprintln(bw, strings.Join(values, "\t"))
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,234 @@
+// Command gn-annotate annotates TempoMessage JSON, protobuf, MAF or VCF files
+// with Genome Nexus and writes them back in the same format.
+//
+//	gn-annotate -i sample.maf -o sample.annotated.maf -cache-dir ~/.cache/gn-annotate
+//
+// The Genome Nexus token is read from the environment variable named by
+// -token-env (GN_TOKEN by default) or from -token-file, never from the command
+// line. The exit code is 0 on success, 1 when the input could not be read or
+// written or a Genome Nexus request failed, and 2 when more events failed to
+// annotate than the -max-failed or -max-failed-fraction thresholds allow. The
+// output is written in both of the last two cases, with the failed events
+// marked in their annotation status.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	gn "github.com/genome-nexus/genome-nexus-go"
+)
+
+const (
+	exitOK        = 0
+	exitError     = 1
+	exitThreshold = 2
+)
+
+type config struct {
+	input              string
+	output             string
+	format             string
+	sample             string
+	serverURL          string
+	tokenEnv           string
+	tokenFile          string
+	isoform            string
+	fields             string
+	batchSize          int
+	concurrency        int
+	retries            int
+	cacheDir           string
+	stripMatchingBases string
+	gnomad             []gn.GnomadSource
+	maxFailed          int
+	maxFailedFraction  float64
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+	var cfg config
+	fs := flag.NewFlagSet("gn-annotate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfg.input, "i", "-", "input file, - for stdin")
+	fs.StringVar(&cfg.output, "o", "-", "output file, - for stdout")
+	fs.StringVar(&cfg.format, "format", "", "input and output format: json, proto, maf or vcf (default from the input file extension)")
+	fs.StringVar(&cfg.sample, "sample", "", "sample id for VCF input (default the first VCF sample column)")
+	fs.StringVar(&cfg.serverURL, "server", "https://www.genomenexus.org", "Genome Nexus server URL")
+	fs.StringVar(&cfg.tokenEnv, "token-env", "GN_TOKEN", "environment variable holding the Genome Nexus token")
+	fs.StringVar(&cfg.tokenFile, "token-file", "", "file holding the Genome Nexus token, overrides -token-env")
+	fs.StringVar(&cfg.isoform, "isoform", "mskcc", "isoform override source")
+	fs.StringVar(&cfg.fields, "fields", "", "comma separated Genome Nexus enrichment fields (default annotation_summary,my_variant_info,mutation_assessor)")
+	fs.IntVar(&cfg.batchSize, "batch-size", 200, "genomic locations per Genome Nexus request")
+	fs.IntVar(&cfg.concurrency, "concurrency", 1, "Genome Nexus requests sent at the same time")
+	fs.IntVar(&cfg.retries, "retries", 0, "retries of failed Genome Nexus requests")
+	fs.StringVar(&cfg.cacheDir, "cache-dir", "", "directory caching annotations between runs")
+	fs.StringVar(&cfg.stripMatchingBases, "strip-matching-bases", gn.StripMatchingBasesAll, "strip allele bases shared by the reference and tumor alleles: all, first or none")
+	gnomad := fs.String("gnomad", "exome", "comma separated gnomAD data sets the allele frequencies are read from, in order of preference: exome, genome or max")
+	fs.IntVar(&cfg.maxFailed, "max-failed", -1, "exit with code 2 when more events fail to annotate, -1 for no limit")
+	fs.Float64Var(&cfg.maxFailedFraction, "max-failed-fraction", -1, "exit with code 2 when a larger fraction of events fails to annotate, -1 for no limit")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	switch cfg.stripMatchingBases {
+	case gn.StripMatchingBasesAll, gn.StripMatchingBasesFirst, gn.StripMatchingBasesNone:
+	default:
+		return cfg, fmt.Errorf("-strip-matching-bases: %q needs to be all, first or none", cfg.stripMatchingBases)
+	}
+	for _, source := range strings.Split(*gnomad, ",") {
+		switch s := gn.GnomadSource(strings.TrimSpace(source)); s {
+		case gn.GnomadSourceExome, gn.GnomadSourceGenome, gn.GnomadSourceMax:
+			cfg.gnomad = append(cfg.gnomad, s)
+		default:
+			return cfg, fmt.Errorf("-gnomad: %q needs to be exome, genome or max", source)
+		}
+	}
+	if cfg.format == "" {
+		cfg.format = formatFromPath(cfg.input)
+		if cfg.format == "" {
+			return cfg, fmt.Errorf("-format is required when it cannot be told from the input file name %q", cfg.input)
+		}
+	}
+	if _, ok := formats[cfg.format]; !ok {
+		return cfg, fmt.Errorf("-format: %q needs to be json, proto, maf or vcf", cfg.format)
+	}
+	return cfg, nil
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, err := parseFlags(args, stderr)
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "gn-annotate: %v\n", err)
+		}
+		return exitError
+	}
+	annotator, err := newAnnotator(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "gn-annotate: %v\n", err)
+		return exitError
+	}
+
+	in := stdin
+	if cfg.input != "-" {
+		f, err := os.Open(cfg.input)
+		if err != nil {
+			fmt.Fprintf(stderr, "gn-annotate: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		in = f
+	}
+	doc, err := formats[cfg.format](in, cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "gn-annotate: failed to read %s input: %v\n", cfg.format, err)
+		return exitError
+	}
+
+	mr, annotateErr := annotator.AnnotateTempoMessages(cfg.isoform, doc.messages())
+	if annotateErr != nil {
+		fmt.Fprintf(stderr, "gn-annotate: %v\n", annotateErr)
+	}
+
+	if err := writeOutput(cfg.output, stdout, doc); err != nil {
+		fmt.Fprintf(stderr, "gn-annotate: failed to write %s output: %v\n", cfg.format, err)
+		return exitError
+	}
+
+	failed := summarize(stderr, mr)
+	if annotateErr != nil {
+		return exitError
+	}
+	if cfg.maxFailed >= 0 && failed > cfg.maxFailed {
+		fmt.Fprintf(stderr, "gn-annotate: %d events failed, more than -max-failed %d\n", failed, cfg.maxFailed)
+		return exitThreshold
+	}
+	if cfg.maxFailedFraction >= 0 && mr.TotalEvents > 0 && float64(failed)/float64(mr.TotalEvents) > cfg.maxFailedFraction {
+		fmt.Fprintf(stderr, "gn-annotate: %d of %d events failed, more than -max-failed-fraction %g\n", failed, mr.TotalEvents, cfg.maxFailedFraction)
+		return exitThreshold
+	}
+	return exitOK
+}
+
+func newAnnotator(cfg config) (gn.GNAnnotator, error) {
+	token := os.Getenv(cfg.tokenEnv)
+	if cfg.tokenFile != "" {
+		b, err := os.ReadFile(cfg.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token file: %v", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	opts := []gn.Option{
+		gn.WithBatchSize(cfg.batchSize),
+		gn.WithConcurrency(cfg.concurrency),
+		gn.WithStripMatchingBases(cfg.stripMatchingBases),
+		gn.WithGnomadPreference(cfg.gnomad...),
+	}
+	if cfg.retries > 0 {
+		opts = append(opts, gn.WithRetries(cfg.retries, time.Second))
+	}
+	if cfg.fields != "" {
+		opts = append(opts, gn.WithEnrichmentFields(strings.Split(cfg.fields, ",")...))
+	}
+	if cfg.cacheDir != "" {
+		cache, err := gn.NewFileCache(cfg.cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, gn.WithCache(cache))
+	}
+	return gn.NewGNAnnotatorService(context.Background(), token, cfg.serverURL, opts...)
+}
+
+func writeOutput(path string, stdout io.Writer, doc document) error {
+	if path == "-" {
+		return doc.write(stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := doc.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// summarize writes the annotation outcome to w and returns the number of
+// events that failed to annotate.
+func summarize(w io.Writer, mr *gn.MessagesReport) int {
+	failed := 0
+	counts := make(map[gn.AnnotationStatusCode]int)
+	for _, r := range mr.Reports {
+		failed += r.Failed()
+		for code, n := range r.StatusCounts {
+			counts[code] += n
+		}
+	}
+	fmt.Fprintf(w, "gn-annotate: %d messages, %d events, %d unique genomic locations, %d cache hits, %d retries\n",
+		len(mr.Reports), mr.TotalEvents, mr.UniqueQueryKeys, mr.CacheHits, mr.Retries)
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "gn-annotate: %s: %d\n", code, counts[gn.AnnotationStatusCode(code)])
+	}
+	return failed
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	"github.com/genome-nexus/genome-nexus-go/internal/gntest"
+)
+
+// newFakeGNServer starts a Genome Nexus stand-in annotating every location
+// with hugoSymbol, or returning no annotations when hugoSymbol is empty.
+func newFakeGNServer(t *testing.T, hugoSymbol string) *httptest.Server {
+	return gntest.NewGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return gntest.Annotation(gl, hugoSymbol), hugoSymbol != ""
+	})
+}
+
+const testMAF = "#version 2.4\n" +
+	"Hugo_Symbol\tChromosome\tStart_Position\tEnd_Position\tReference_Allele\tTumor_Seq_Allele1\tTumor_Seq_Allele2\tTumor_Sample_Barcode\tcustom\n" +
+	"\t7\t55249071\t55249071\tC\tC\tT\ts1\tkeep-1\n" +
+	"\t12\t25398284\t25398284\tC\tC\tA\ts2\tkeep-2\n"
+
+func TestRunMAF(t *testing.T) {
+	server := newFakeGNServer(t, "EGFR")
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.maf")
+	output := filepath.Join(dir, "out.maf")
+	if err := os.WriteFile(input, []byte(testMAF), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	code := run([]string{"-i", input, "-o", output, "-server", server.URL, "-cache-dir", filepath.Join(dir, "cache"), "-max-failed", "0"}, nil, nil, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d but got %d: %s", exitOK, code, stderr.String())
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 4 || lines[0] != "#version 2.4" {
+		t.Fatalf("unexpected output:\n%s", b)
+	}
+	header := strings.Split(lines[1], "\t")
+	row := strings.Split(lines[3], "\t")
+	values := make(map[string]string)
+	for i, name := range header {
+		values[name] = row[i]
+	}
+	if values["Hugo_Symbol"] != "EGFR" || values["Tumor_Sample_Barcode"] != "s2" || values["custom"] != "keep-2" || values["Annotation_Status"] != "SUCCESS" {
+		t.Errorf("unexpected output row: %v", values)
+	}
+}
+
+func TestRunFailureThreshold(t *testing.T) {
+	server := newFakeGNServer(t, "")
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.maf")
+	if err := os.WriteFile(input, []byte(testMAF), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-i", input, "-server", server.URL, "-max-failed-fraction", "0.5"}, nil, &stdout, &stderr); code != exitThreshold {
+		t.Errorf("expected exit code %d but got %d: %s", exitThreshold, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "FAILURE: NO_RESPONSE") {
+		t.Errorf("expected the output to be written before failing but got:\n%s", stdout.String())
+	}
+	for _, flag := range [][]string{{"-strip-matching-bases", "some"}, {"-gnomad", "exome,all"}} {
+		if code := run(append([]string{"-i", input, "-server", server.URL}, flag...), nil, &stdout, &stderr); code != exitError {
+			t.Errorf("expected exit code %d for an invalid %s but got %d", exitError, flag[0], code)
+		}
+	}
+}
+
+func TestRunGenomeNexusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-format", "maf", "-server", server.URL}, strings.NewReader(testMAF), &stdout, &stderr); code != exitError {
+		t.Errorf("expected exit code %d but got %d: %s", exitError, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "FAILURE: ") {
+		t.Errorf("expected the output to be written with the failures but got:\n%s", stdout.String())
+	}
+}
+
+func TestVCFEvent(t *testing.T) {
+	tests := []struct {
+		pos      int64
+		ref, alt string
+		expected string
+	}{
+		{100, "A", "T", "100,100,A,T"},
+		{100, "AT", "A", "101,101,T,-"},
+		{100, "A", "AT", "100,101,-,T"},
+		{100, "ACG", "AT", "101,102,CG,T"},
+	}
+	for _, test := range tests {
+		e := vcfEvent("1", test.pos, test.ref, test.alt)
+		got := strings.Join([]string{e.StartPosition, e.EndPosition, e.ReferenceAllele, e.TumorSeqAllele2}, ",")
+		if got != test.expected {
+			t.Errorf("%d %s>%s: expected %s but got %s", test.pos, test.ref, test.alt, test.expected, got)
+		}
+	}
+	if vcfEvent("1", 100, "A", "<DEL>") != nil {
+		t.Errorf("expected symbolic alleles to be skipped")
+	}
+}
+
+func TestRunVCF(t *testing.T) {
+	server := newFakeGNServer(t, "KRAS")
+	vcf := "##fileformat=VCFv4.2\n" +
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\ttumor\n" +
+		"12\t25398284\t.\tC\tA,<DEL>\t.\tPASS\tDP=10\tGT\t0/1\n"
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-format", "vcf", "-server", server.URL}, strings.NewReader(vcf), &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected exit code %d but got %d: %s", exitOK, code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	record := strings.Split(lines[len(lines)-1], "\t")
+	if !strings.HasPrefix(record[7], "DP=10;GN_HUGO_SYMBOL=KRAS,.;") {
+		t.Errorf("unexpected INFO %q", record[7])
+	}
+	if !strings.Contains(stdout.String(), "##INFO=<ID=GN_HGVSP_SHORT,Number=A") {
+		t.Errorf("expected the INFO header lines to be added")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// vcfInfoField is an INFO field added to annotated VCF records, holding one
+// value per ALT allele.
+type vcfInfoField struct {
+	id          string
+	description string
+	get         func(e *tt.Event) string
+}
+
+var vcfInfoFields = []vcfInfoField{
+	{"GN_HUGO_SYMBOL", "Genome Nexus Hugo symbol", func(e *tt.Event) string { return e.HugoSymbol }},
+	{"GN_VARIANT_CLASSIFICATION", "Genome Nexus variant classification", func(e *tt.Event) string { return e.VariantClassification }},
+	{"GN_TRANSCRIPT_ID", "Genome Nexus transcript id", func(e *tt.Event) string { return e.TranscriptId }},
+	{"GN_HGVSC", "Genome Nexus HGVSc", func(e *tt.Event) string { return e.Hgvsc }},
+	{"GN_HGVSP_SHORT", "Genome Nexus HGVSp_Short", func(e *tt.Event) string { return e.HgvspShort }},
+	{"GN_ANNOTATION_STATUS", "Genome Nexus annotation status", func(e *tt.Event) string { return e.AnnotationStatus }},
+}
+
+// vcfDocument is a VCF file annotated as a single TempoMessage. Annotations
+// are written back as INFO fields.
+type vcfDocument struct {
+	meta    []string
+	header  string
+	records []vcfRecord
+	message *tt.TempoMessage
+}
+
+type vcfRecord struct {
+	fields []string
+	// events holds the event of each ALT allele, nil for alleles that are not
+	// annotated (e.g. symbolic alleles).
+	events []*tt.Event
+}
+
+// readVCF reads a VCF file, creating one event per ALT allele. The sample id
+// is cfg.sample, the first sample column or the input file name.
+func readVCF(r io.Reader, cfg config) (document, error) {
+	doc := &vcfDocument{message: &tt.TempoMessage{CmoSampleId: cfg.sample}}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), "\r")
+		switch {
+		case strings.HasPrefix(text, "##"):
+			doc.meta = append(doc.meta, text)
+			continue
+		case strings.HasPrefix(text, "#"):
+			doc.header = text
+			columns := strings.Split(text, "\t")
+			if doc.message.CmoSampleId == "" && len(columns) > 9 {
+				doc.message.CmoSampleId = columns[9]
+			}
+			continue
+		case strings.TrimSpace(text) == "":
+			continue
+		}
+		if doc.header == "" {
+			return nil, fmt.Errorf("line %d: record before the #CHROM header", line)
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("line %d: %d columns, expected at least 8", line, len(fields))
+		}
+		pos, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid position %q", line, fields[1])
+		}
+		rec := vcfRecord{fields: fields}
+		for _, alt := range strings.Split(fields[4], ",") {
+			e := vcfEvent(fields[0], pos, strings.ToUpper(fields[3]), strings.ToUpper(alt))
+			if e != nil {
+				doc.message.Events = append(doc.message.Events, e)
+			}
+			rec.events = append(rec.events, e)
+		}
+		doc.records = append(doc.records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if doc.message.CmoSampleId == "" && cfg.input != "-" {
+		doc.message.CmoSampleId = strings.TrimSuffix(filepath.Base(cfg.input), filepath.Ext(cfg.input))
+	}
+	for _, e := range doc.message.Events {
+		e.TumorSampleBarcode = doc.message.CmoSampleId
+	}
+	return doc, nil
+}
+
+// vcfEvent converts a VCF allele to an event in MAF coordinates, removing the
+// bases shared by REF and ALT. It returns nil for symbolic, missing and
+// spanning deletion alleles.
+func vcfEvent(chromosome string, pos int64, ref, alt string) *tt.Event {
+	if alt == "" || alt == "." || alt == "*" || strings.ContainsAny(alt, "<>[]") {
+		return nil
+	}
+	shared := 0
+	for shared < len(ref) && shared < len(alt) && ref[shared] == alt[shared] {
+		shared++
+	}
+	ref, alt = ref[shared:], alt[shared:]
+	start := pos + int64(shared)
+	end := start + int64(len(ref)) - 1
+	if ref == "" {
+		// insertion: MAF positions are the bases on either side
+		ref = "-"
+		start, end = start-1, start
+	}
+	if alt == "" {
+		alt = "-"
+	}
+	return &tt.Event{
+		Chromosome:      chromosome,
+		StartPosition:   strconv.FormatInt(start, 10),
+		EndPosition:     strconv.FormatInt(end, 10),
+		ReferenceAllele: ref,
+		TumorSeqAllele1: ref,
+		TumorSeqAllele2: alt,
+	}
+}
+
+func (d *vcfDocument) messages() []*tt.TempoMessage {
+	return []*tt.TempoMessage{d.message}
+}
+
+func (d *vcfDocument) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, m := range d.meta {
+		fmt.Fprintln(bw, m)
+	}
+	for _, f := range vcfInfoFields {
+		fmt.Fprintf(bw, "##INFO=<ID=%s,Number=A,Type=String,Description=\"%s\">\n", f.id, f.description)
+	}
+	fmt.Fprintln(bw, d.header)
+	for _, rec := range d.records {
+		fields := append([]string{}, rec.fields...)
+		info := make([]string, 0, len(vcfInfoFields)+1)
+		if fields[7] != "" && fields[7] != "." {
+			info = append(info, fields[7])
+		}
+		for _, f := range vcfInfoFields {
+			values := make([]string, len(rec.events))
+			for i, e := range rec.events {
+				values[i] = "."
+				if e != nil && f.get(e) != "" {
+					values[i] = vcfInfoValue(f.get(e))
+				}
+			}
+			info = append(info, f.id+"="+strings.Join(values, ","))
+		}
+		fields[7] = strings.Join(info, ";")
+		fmt.Fprintln(bw, strings.Join(fields, "\t"))
+	}
+	return bw.Flush()
+}
+
+// vcfInfoValue percent-encodes the characters that cannot appear in an INFO value.
+func vcfInfoValue(s string) string {
+	return strings.NewReplacer(
+		"%", "%25", ";", "%3B", "=", "%3D", ",", "%2C", " ", "%20", "\t", "%09",
+	).Replace(s)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	gn "github.com/genome-nexus/genome-nexus-go"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeJSON     = "application/json"
+	contentTypeProtobuf = "application/x-protobuf"
+)
+
+// server annotates TempoMessages posted over HTTP.
+type server struct {
+	annotator gn.GNAnnotator
+	// isoformOverrideSource is used when a request does not set one.
+	isoformOverrideSource string
+	maxBodyBytes          int64
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /annotate", s.handleAnnotate)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /readyz", s.handleReady)
+	return mux
+}
+
+// handleAnnotate annotates the TempoMessage in the request body and returns
+// it in the same content type. The isoformOverrideSource query parameter
+// overrides the server default. The annotation outcome is summarized in the
+// X-Annotation-* response headers.
+func (s *server) handleAnnotate(w http.ResponseWriter, r *http.Request) {
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != contentTypeJSON && contentType != contentTypeProtobuf) {
+		http.Error(w, fmt.Sprintf("Content-Type needs to be %s or %s", contentTypeJSON, contentTypeProtobuf), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.As(err, new(*http.MaxBytesError)) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, fmt.Sprintf("failed to read the request body: %v", err), code)
+		return
+	}
+	tm := &tt.TempoMessage{}
+	if contentType == contentTypeProtobuf {
+		err = proto.Unmarshal(body, tm)
+	} else {
+		err = json.Unmarshal(body, tm)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid TempoMessage: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	isoformOverrideSource := r.URL.Query().Get("isoformOverrideSource")
+	if isoformOverrideSource == "" {
+		isoformOverrideSource = s.isoformOverrideSource
+	}
+	// Genome Nexus errors hold its URL and response body, so they are only
+	// logged and clients get a fixed message.
+	// requests to Genome Nexus are cancelled when the client goes away
+	report, err := s.annotator.AnnotateTempoMessageEventsContext(r.Context(), isoformOverrideSource, tm)
+	if ctxErr := r.Context().Err(); ctxErr != nil {
+		log.Printf("gn-annotator-server: annotation cancelled: %v", ctxErr)
+		http.Error(w, "annotation cancelled", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil && report.Succeeded() == 0 && len(tm.Events) > 0 {
+		log.Printf("gn-annotator-server: Genome Nexus request failed: %v", err)
+		http.Error(w, "Genome Nexus request failed", http.StatusBadGateway)
+		return
+	}
+
+	var out []byte
+	if contentType == contentTypeProtobuf {
+		out, err = proto.Marshal(tm)
+	} else {
+		out, err = json.Marshal(tm)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode the annotated TempoMessage: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Annotation-Succeeded", strconv.Itoa(report.Succeeded()))
+	w.Header().Set("X-Annotation-Failed", strconv.Itoa(report.Failed()))
+	w.Header().Set("X-Annotation-Version", report.AnnotationVersion())
+	w.Write(out)
+}
+
+// handleHealth reports that the server is running.
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// handleReady reports whether Genome Nexus can be reached, returning its
+// version info when it can.
+func (s *server) handleReady(w http.ResponseWriter, r *http.Request) {
+	info, err := s.annotator.GetGenomeNexusInfo()
+	if err != nil {
+		log.Printf("gn-annotator-server: Genome Nexus is not ready: %v", err)
+		http.Error(w, "Genome Nexus is not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	json.NewEncoder(w).Encode(info)
+}
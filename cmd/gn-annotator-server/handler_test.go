@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/genome-nexus/genome-nexus-go/internal/gntest"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAnnotateJSON(t *testing.T) {
+	annotator := &gntest.Annotator{HugoSymbol: "EGFR"}
+	s := &server{annotator: annotator, isoformOverrideSource: "mskcc", maxBodyBytes: 1 << 20}
+	body, _ := json.Marshal(gntest.NewTestMessage("s1"))
+
+	req := httptest.NewRequest(http.MethodPost, "/annotate?isoformOverrideSource=uniprot", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+	var tm tt.TempoMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &tm); err != nil {
+		t.Fatalf("failed to decode the response: %v", err)
+	}
+	if tm.Events[0].HugoSymbol != "EGFR" || rec.Header().Get("X-Annotation-Succeeded") != "1" {
+		t.Errorf("unexpected response %+v with headers %v", tm.Events[0], rec.Header())
+	}
+	if isoforms := annotator.Isoforms(); len(isoforms) != 1 || isoforms[0] != "uniprot" {
+		t.Errorf("expected the isoform override source of the request to be used but got %v", isoforms)
+	}
+}
+
+func TestAnnotateProtobuf(t *testing.T) {
+	s := &server{annotator: &gntest.Annotator{HugoSymbol: "EGFR"}, isoformOverrideSource: "mskcc", maxBodyBytes: 1 << 20}
+	body, _ := proto.Marshal(gntest.NewTestMessage("s1"))
+
+	req := httptest.NewRequest(http.MethodPost, "/annotate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("expected a protobuf response but got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	tm := &tt.TempoMessage{}
+	if err := proto.Unmarshal(rec.Body.Bytes(), tm); err != nil || tm.Events[0].HugoSymbol != "EGFR" {
+		t.Errorf("unexpected response %v (%v)", tm, err)
+	}
+}
+
+func TestAnnotateErrors(t *testing.T) {
+	failing := &server{annotator: &gntest.Annotator{Err: errors.New("connection refused to https://gn.example.org")}, maxBodyBytes: 1 << 20}
+	body, _ := json.Marshal(gntest.NewTestMessage("s1"))
+	tests := []struct {
+		name        string
+		s           *server
+		contentType string
+		body        io.Reader
+		expected    int
+	}{
+		{"unsupported content type", failing, "text/plain", bytes.NewReader(body), http.StatusUnsupportedMediaType},
+		{"invalid message", failing, "application/json", strings.NewReader("{"), http.StatusBadRequest},
+		{"too large", &server{annotator: failing.annotator, maxBodyBytes: 4}, "application/json", bytes.NewReader(body), http.StatusRequestEntityTooLarge},
+		{"unreadable body", failing, "application/json", iotest.ErrReader(errors.New("reset")), http.StatusBadRequest},
+		{"Genome Nexus down", failing, "application/json", bytes.NewReader(body), http.StatusBadGateway},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/annotate", test.body)
+		req.Header.Set("Content-Type", test.contentType)
+		rec := httptest.NewRecorder()
+		test.s.routes().ServeHTTP(rec, req)
+		if rec.Code != test.expected {
+			t.Errorf("%s: expected status %d but got %d", test.name, test.expected, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "gn.example.org") {
+			t.Errorf("%s: expected the Genome Nexus error not to be returned but got %q", test.name, rec.Body.String())
+		}
+	}
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	tests := []struct {
+		path     string
+		err      error
+		expected int
+	}{
+		{"/healthz", errors.New("down"), http.StatusOK},
+		{"/readyz", nil, http.StatusOK},
+		{"/readyz", errors.New("down"), http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		s := &server{annotator: &gntest.Annotator{Err: test.err}}
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if rec.Code != test.expected {
+			t.Errorf("%s (err: %v): expected status %d but got %d", test.path, test.err, test.expected, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Command gn-annotator-server annotates TempoMessages posted over HTTP with
+// Genome Nexus.
+//
+//	POST /annotate   TempoMessage as application/json or application/x-protobuf
+//	GET  /healthz    liveness
+//	GET  /readyz     readiness, checks that Genome Nexus can be reached
+//
+// All requests share one annotation cache, bounded by -cache-entries unless
+// -cache-dir is set, and one pool of connections to Genome Nexus. With
+// -grpc-addr the annotatorgrpc Annotator service is served as well. The Genome
+// Nexus token is read from the environment variable named by -token-env
+// (GN_TOKEN by default) or from -token-file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	gn "github.com/genome-nexus/genome-nexus-go"
+	"github.com/genome-nexus/genome-nexus-go/annotatorgrpc"
+	"google.golang.org/grpc"
+)
+
+type config struct {
+	addr            string
+	grpcAddr        string
+	serverURL       string
+	tokenEnv        string
+	tokenFile       string
+	isoform         string
+	fields          string
+	batchSize       int
+	concurrency     int
+	retries         int
+	streamWindow    time.Duration
+	cacheDir        string
+	cacheEntries    int
+	maxIdleConns    int
+	requestTimeout  time.Duration
+	maxBodyBytes    int64
+	shutdownTimeout time.Duration
+}
+
+func parseFlags() config {
+	var cfg config
+	flag.StringVar(&cfg.addr, "addr", ":8080", "address to listen on")
+	flag.StringVar(&cfg.grpcAddr, "grpc-addr", "", "address to serve the gRPC Annotator service on, disabled when empty")
+	flag.StringVar(&cfg.serverURL, "server", "https://www.genomenexus.org", "Genome Nexus server URL")
+	flag.StringVar(&cfg.tokenEnv, "token-env", "GN_TOKEN", "environment variable holding the Genome Nexus token")
+	flag.StringVar(&cfg.tokenFile, "token-file", "", "file holding the Genome Nexus token, overrides -token-env")
+	flag.StringVar(&cfg.isoform, "isoform", "mskcc", "default isoform override source")
+	flag.StringVar(&cfg.fields, "fields", "", "comma separated Genome Nexus enrichment fields")
+	flag.IntVar(&cfg.batchSize, "batch-size", 200, "genomic locations per Genome Nexus request")
+	flag.IntVar(&cfg.concurrency, "concurrency", 4, "Genome Nexus requests sent at the same time per annotated message")
+	flag.IntVar(&cfg.retries, "retries", 2, "retries of failed Genome Nexus requests")
+	flag.DurationVar(&cfg.streamWindow, "stream-window", 100*time.Millisecond, "time gRPC stream messages wait to be annotated together")
+	flag.StringVar(&cfg.cacheDir, "cache-dir", "", "directory caching annotations, in memory when empty")
+	flag.IntVar(&cfg.cacheEntries, "cache-entries", 100000, "annotations cached in memory when -cache-dir is empty, 0 disables the cache")
+	flag.IntVar(&cfg.maxIdleConns, "max-idle-conns", 32, "idle connections kept open to Genome Nexus")
+	flag.DurationVar(&cfg.requestTimeout, "request-timeout", 2*time.Minute, "timeout of a single Genome Nexus request")
+	flag.Int64Var(&cfg.maxBodyBytes, "max-body-bytes", 32<<20, "largest accepted request body")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "time given to running requests on shutdown")
+	flag.Parse()
+	return cfg
+}
+
+func main() {
+	cfg := parseFlags()
+	annotator, err := newAnnotator(cfg)
+	if err != nil {
+		log.Fatalf("gn-annotator-server: %v", err)
+	}
+	s := &server{
+		annotator:             annotator,
+		isoformOverrideSource: cfg.isoform,
+		maxBodyBytes:          cfg.maxBodyBytes,
+	}
+	httpServer := &http.Server{
+		Addr:              cfg.addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.grpcAddr != "" {
+		lis, err := net.Listen("tcp", cfg.grpcAddr)
+		if err != nil {
+			log.Fatalf("gn-annotator-server: %v", err)
+		}
+		grpcServer = grpc.NewServer()
+		annotatorgrpc.RegisterAnnotatorServer(grpcServer, annotatorgrpc.NewServer(annotator, cfg.isoform))
+		go func() {
+			log.Printf("gn-annotator-server: serving gRPC on %s", cfg.grpcAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("gn-annotator-server: %v", err)
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+		defer cancel()
+		if grpcServer != nil {
+			go func() {
+				<-shutdownCtx.Done()
+				grpcServer.Stop()
+			}()
+			grpcServer.GracefulStop()
+		}
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("gn-annotator-server: listening on %s, annotating with %s", cfg.addr, cfg.serverURL)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("gn-annotator-server: %v", err)
+	}
+}
+
+func newAnnotator(cfg config) (gn.GNAnnotator, error) {
+	token := os.Getenv(cfg.tokenEnv)
+	if cfg.tokenFile != "" {
+		b, err := os.ReadFile(cfg.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token file: %v", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+
+	var cache gn.AnnotationCache
+	switch {
+	case cfg.cacheDir != "":
+		fc, err := gn.NewFileCache(cfg.cacheDir)
+		if err != nil {
+			return nil, err
+		}
+		cache = fc
+	case cfg.cacheEntries > 0:
+		cache = gn.NewLRUCache(cfg.cacheEntries)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.maxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.maxIdleConns
+
+	opts := []gn.Option{
+		gn.WithHTTPClient(&http.Client{Transport: transport, Timeout: cfg.requestTimeout}),
+		gn.WithBatchSize(cfg.batchSize),
+		gn.WithConcurrency(cfg.concurrency),
+		gn.WithRetries(cfg.retries, time.Second),
+		// HTTP requests and unary gRPC calls close their input after a single
+		// message, which flushes it right away; the window only batches the
+		// messages of gRPC streams
+		gn.WithStreamWindow(cfg.batchSize, cfg.streamWindow),
+	}
+	if cache != nil {
+		opts = append(opts, gn.WithCache(cache))
+	}
+	if cfg.fields != "" {
+		opts = append(opts, gn.WithEnrichmentFields(strings.Split(cfg.fields, ",")...))
+	}
+	return gn.NewGNAnnotatorService(context.Background(), token, cfg.serverURL, opts...)
+}
@@ -0,0 +1,279 @@
+package genome_nexus_annotator_go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// eventField is an Event field compared between annotation runs.
+type eventField struct {
+	name string
+	get  func(e *tt.Event) string
+	// significant fields change how a variant is reported clinically.
+	significant bool
+}
+
+// annotatedEventFields lists the Event fields checked when comparing two
+// annotations of the same event.
+var annotatedEventFields = []eventField{
+	{"Chromosome", func(e *tt.Event) string { return e.Chromosome }, true},
+	{"StartPosition", func(e *tt.Event) string { return e.StartPosition }, true},
+	{"EndPosition", func(e *tt.Event) string { return e.EndPosition }, true},
+	{"ReferenceAllele", func(e *tt.Event) string { return e.ReferenceAllele }, true},
+	{"TumorSeqAllele1", func(e *tt.Event) string { return e.TumorSeqAllele1 }, true},
+	{"TumorSeqAllele2", func(e *tt.Event) string { return e.TumorSeqAllele2 }, true},
+	{"Strand", func(e *tt.Event) string { return e.Strand }, false},
+	{"NcbiBuild", func(e *tt.Event) string { return e.NcbiBuild }, false},
+	{"HugoSymbol", func(e *tt.Event) string { return e.HugoSymbol }, true},
+	{"VariantClassification", func(e *tt.Event) string { return e.VariantClassification }, true},
+	{"VariantType", func(e *tt.Event) string { return e.VariantType }, true},
+	{"DbsnpRs", func(e *tt.Event) string { return e.DbsnpRs }, false},
+	{"Hgvsp", func(e *tt.Event) string { return e.Hgvsp }, true},
+	{"HgvspShort", func(e *tt.Event) string { return e.HgvspShort }, true},
+	{"Hgvsc", func(e *tt.Event) string { return e.Hgvsc }, true},
+	{"TranscriptId", func(e *tt.Event) string { return e.TranscriptId }, true},
+	{"Refseq", func(e *tt.Event) string { return e.Refseq }, false},
+	{"Center", func(e *tt.Event) string { return e.Center }, false},
+	{"Consequence", func(e *tt.Event) string { return e.Consequence }, false},
+	{"DbsnpValStatus", func(e *tt.Event) string { return e.DbsnpValStatus }, false},
+	{"MatchedNormSampleBarcode", func(e *tt.Event) string { return e.MatchedNormSampleBarcode }, false},
+	{"MatchNormSeqAllele1", func(e *tt.Event) string { return e.MatchNormSeqAllele1 }, false},
+	{"MatchNormSeqAllele2", func(e *tt.Event) string { return e.MatchNormSeqAllele2 }, false},
+	{"VerificationStatus", func(e *tt.Event) string { return e.VerificationStatus }, false},
+	{"ValidationStatus", func(e *tt.Event) string { return e.ValidationStatus }, false},
+	{"MutationStatus", func(e *tt.Event) string { return e.MutationStatus }, false},
+	{"SequencingPhase", func(e *tt.Event) string { return e.SequencingPhase }, false},
+	{"SequencingSource", func(e *tt.Event) string { return e.SequencingSource }, false},
+	{"ValidationMethod", func(e *tt.Event) string { return e.ValidationMethod }, false},
+	{"Score", func(e *tt.Event) string { return e.Score }, false},
+	{"BamFile", func(e *tt.Event) string { return e.BamFile }, false},
+	{"Sequencer", func(e *tt.Event) string { return e.Sequencer }, false},
+	{"TRefCount", func(e *tt.Event) string { return e.TRefCount }, false},
+	{"TAltCount", func(e *tt.Event) string { return e.TAltCount }, false},
+	{"NRefCount", func(e *tt.Event) string { return e.NRefCount }, false},
+	{"NAltCount", func(e *tt.Event) string { return e.NAltCount }, false},
+	{"ProteinPosition", func(e *tt.Event) string { return e.ProteinPosition }, true},
+	{"Codons", func(e *tt.Event) string { return e.Codons }, false},
+	{"ExonNumber", func(e *tt.Event) string { return e.ExonNumber }, false},
+	{"PolyphenPrediction", func(e *tt.Event) string { return e.PolyphenPrediction }, false},
+	{"PolyphenScore", func(e *tt.Event) string { return e.PolyphenScore }, false},
+	{"SiftPrediction", func(e *tt.Event) string { return e.SiftPrediction }, false},
+	{"SiftScore", func(e *tt.Event) string { return e.SiftScore }, false},
+	{"AnnotationStatus", func(e *tt.Event) string { return e.AnnotationStatus }, true},
+	{"EntrezGeneId", func(e *tt.Event) string { return e.EntrezGeneId }, true},
+}
+
+// sameAnnotation reports whether two values of an annotated field are equal.
+// Values differing only in case are the same.
+func sameAnnotation(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// DiffSignificance classifies a change between two annotation runs.
+type DiffSignificance string
+
+const (
+	// DiffSignificant changes how the variant is reported (e.g. gene, HGVSp,
+	// classification or transcript).
+	DiffSignificant DiffSignificance = "SIGNIFICANT"
+	// DiffCosmetic changes only supporting or equivalently written values.
+	DiffCosmetic DiffSignificance = "COSMETIC"
+)
+
+// EventChange describes how an event differs between two annotation runs.
+type EventChange string
+
+const (
+	EventChanged EventChange = "CHANGED"
+	EventAdded   EventChange = "ADDED"
+	EventRemoved EventChange = "REMOVED"
+)
+
+// FieldDiff is a single field that differs between two annotations of an event.
+type FieldDiff struct {
+	Field        string           `json:"field"`
+	Old          string           `json:"old"`
+	New          string           `json:"new"`
+	Significance DiffSignificance `json:"significance"`
+}
+
+// EventDiff describes an event that differs between two annotation runs.
+type EventDiff struct {
+	SampleId string `json:"sampleId"`
+	// QueryKey is the genomic location key the events were matched by.
+	QueryKey     string           `json:"queryKey"`
+	Change       EventChange      `json:"change"`
+	Significance DiffSignificance `json:"significance"`
+	Fields       []FieldDiff      `json:"fields,omitempty"`
+}
+
+// AnnotationDiff is the result of comparing two annotation runs.
+type AnnotationDiff struct {
+	// Events holds the events that differ, in the order of the new run
+	// followed by the events that are missing from it.
+	Events            []EventDiff `json:"events"`
+	MatchedEvents     int         `json:"matchedEvents"`
+	UnchangedEvents   int         `json:"unchangedEvents"`
+	SignificantEvents int         `json:"significantEvents"`
+	CosmeticEvents    int         `json:"cosmeticEvents"`
+	AddedEvents       int         `json:"addedEvents"`
+	RemovedEvents     int         `json:"removedEvents"`
+}
+
+// diffMatchKey identifies an event by sample and genomic location.
+type diffMatchKey struct {
+	sampleId string
+	queryKey string
+}
+
+// eventQueryKey returns the genomic location key of an event, falling back to
+// its raw location fields when the location is not valid.
+func eventQueryKey(e *tt.Event) string {
+	loc, err := getGenomicLocation(e)
+	if err != nil {
+		return buildEventKey(e)
+	}
+	return buildGenomicLocationKey(loc)
+}
+
+// DiffAnnotations compares two annotation runs of the same TempoMessages.
+// Events are matched by sample and genomic location key; events sharing both
+// are matched in order.
+func DiffAnnotations(before, after []*tt.TempoMessage) *AnnotationDiff {
+	d := &AnnotationDiff{Events: make([]EventDiff, 0)}
+
+	unmatched := make(map[diffMatchKey][]*tt.Event)
+	order := make([]diffMatchKey, 0)
+	for _, tm := range before {
+		for _, e := range tm.Events {
+			k := diffMatchKey{tm.CmoSampleId, eventQueryKey(e)}
+			if _, ok := unmatched[k]; !ok {
+				order = append(order, k)
+			}
+			unmatched[k] = append(unmatched[k], e)
+		}
+	}
+
+	for _, tm := range after {
+		for _, e := range tm.Events {
+			k := diffMatchKey{tm.CmoSampleId, eventQueryKey(e)}
+			if len(unmatched[k]) == 0 {
+				d.add(EventDiff{SampleId: k.sampleId, QueryKey: k.queryKey, Change: EventAdded, Significance: DiffSignificant})
+				continue
+			}
+			old := unmatched[k][0]
+			unmatched[k] = unmatched[k][1:]
+			d.MatchedEvents++
+
+			fields := diffEventFields(old, e)
+			if len(fields) == 0 {
+				d.UnchangedEvents++
+				continue
+			}
+			significance := DiffCosmetic
+			for _, f := range fields {
+				if f.Significance == DiffSignificant {
+					significance = DiffSignificant
+					break
+				}
+			}
+			d.add(EventDiff{SampleId: k.sampleId, QueryKey: k.queryKey, Change: EventChanged, Significance: significance, Fields: fields})
+		}
+	}
+
+	for _, k := range order {
+		for range unmatched[k] {
+			d.add(EventDiff{SampleId: k.sampleId, QueryKey: k.queryKey, Change: EventRemoved, Significance: DiffSignificant})
+		}
+	}
+	return d
+}
+
+func (d *AnnotationDiff) add(ed EventDiff) {
+	d.Events = append(d.Events, ed)
+	switch {
+	case ed.Change == EventAdded:
+		d.AddedEvents++
+	case ed.Change == EventRemoved:
+		d.RemovedEvents++
+	case ed.Significance == DiffSignificant:
+		d.SignificantEvents++
+	default:
+		d.CosmeticEvents++
+	}
+}
+
+// diffEventFields returns the annotated fields that differ between two
+// annotations of the same event.
+func diffEventFields(old, new *tt.Event) []FieldDiff {
+	fields := make([]FieldDiff, 0)
+	for _, f := range annotatedEventFields {
+		o, n := f.get(old), f.get(new)
+		if sameAnnotation(o, n) {
+			continue
+		}
+		significance := DiffCosmetic
+		if f.significant && !equivalentAnnotation(f.name, o, n) {
+			significance = DiffSignificant
+		}
+		fields = append(fields, FieldDiff{Field: f.name, Old: o, New: n, Significance: significance})
+	}
+	return fields
+}
+
+// equivalentAnnotation reports whether two different values of a field
+// describe the same thing, e.g. HGVSp written with or without a protein id,
+// or with X instead of * for a stop codon.
+func equivalentAnnotation(field, a, b string) bool {
+	if strings.TrimSpace(a) == strings.TrimSpace(b) {
+		return true
+	}
+	switch field {
+	case "Hgvsp", "HgvspShort":
+		sa, errA := hgvspToShort(a)
+		sb, errB := hgvspToShort(b)
+		return errA == nil && errB == nil && sa == sb
+	case "ProteinPosition":
+		pa, pb := parseVepProteinPosition(a), parseVepProteinPosition(b)
+		return !pa.isEmpty() && pa == pb
+	}
+	return false
+}
+
+// WriteJSON writes the diff as indented JSON.
+func (d *AnnotationDiff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteTSV writes the diff as tab separated values with one row per changed
+// field, and one row without a field for every added or removed event.
+func (d *AnnotationDiff) WriteTSV(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "sample_id\tquery_key\tchange\tsignificance\tfield\told_value\tnew_value"); err != nil {
+		return err
+	}
+	for _, ed := range d.Events {
+		if len(ed.Fields) == 0 {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\t\t\n", tsvValue(ed.SampleId), tsvValue(ed.QueryKey), ed.Change, ed.Significance); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, f := range ed.Fields {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", tsvValue(ed.SampleId), tsvValue(ed.QueryKey), ed.Change, f.Significance, f.Field, tsvValue(f.Old), tsvValue(f.New)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tsvValue replaces the tabs and line breaks that would break a TSV row.
+func tsvValue(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,113 @@
+package genome_nexus_annotator_go
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func copyTestSetMessages(t *testing.T) []*tt.TempoMessage {
+	testset := readTestSetJSON(t, mutationRecordsJSON)
+	tms := make([]*tt.TempoMessage, len(testset.Records))
+	for i := range testset.Records {
+		b, _ := json.Marshal(&testset.Records[i])
+		tms[i] = &tt.TempoMessage{}
+		if err := json.Unmarshal(b, tms[i]); err != nil {
+			t.Fatalf("Failed to copy TempoMessage: %v", err)
+		}
+	}
+	return tms
+}
+
+func TestDiffAnnotationsUnchanged(t *testing.T) {
+	before, after := copyTestSetMessages(t), copyTestSetMessages(t)
+	total := 0
+	for _, tm := range after {
+		total += len(tm.Events)
+		for _, e := range tm.Events {
+			e.HugoSymbol = strings.ToLower(e.HugoSymbol)
+		}
+	}
+
+	d := DiffAnnotations(before, after)
+	if len(d.Events) != 0 {
+		t.Errorf("expected no differences but got %+v", d.Events)
+	}
+	if d.MatchedEvents != total || d.UnchangedEvents != total {
+		t.Errorf("expected %d matched and unchanged events but got %d and %d", total, d.MatchedEvents, d.UnchangedEvents)
+	}
+}
+
+func TestDiffAnnotations(t *testing.T) {
+	before := []*tt.TempoMessage{{CmoSampleId: "s1", Events: []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+		newTestEvent("12", "25398284", "25398284", "C", "T"),
+		newTestEvent("17", "7577120", "7577120", "C", "T"),
+		newTestEvent("1", "100", "100", "G", "A"),
+	}}}
+	after := []*tt.TempoMessage{{CmoSampleId: "s1", Events: []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+		newTestEvent("12", "25398284", "25398284", "C", "T"),
+		newTestEvent("17", "7577120", "7577120", "C", "T"),
+		newTestEvent("2", "200", "200", "G", "A"),
+	}}}
+	before[0].Events[0].Hgvsp, after[0].Events[0].Hgvsp = "p.Val600Glu", "ENSP00000288602:p.Val600Glu"
+	before[0].Events[0].SiftScore, after[0].Events[0].SiftScore = "0.01", "0.02"
+	before[0].Events[1].HgvspShort, after[0].Events[1].HgvspShort = "p.G12D", "p.G13D"
+	before[0].Events[2].TranscriptId, after[0].Events[2].TranscriptId = "ENST00000269305", "ENST00000413465"
+
+	d := DiffAnnotations(before, after)
+	if d.MatchedEvents != 3 || d.CosmeticEvents != 1 || d.SignificantEvents != 2 || d.AddedEvents != 1 || d.RemovedEvents != 1 {
+		t.Fatalf("unexpected counts: %+v", d)
+	}
+	if len(d.Events) != 5 {
+		t.Fatalf("expected 5 differing events but got %d", len(d.Events))
+	}
+	expected := []struct {
+		change       EventChange
+		significance DiffSignificance
+		fields       int
+	}{
+		{EventChanged, DiffCosmetic, 2},
+		{EventChanged, DiffSignificant, 1},
+		{EventChanged, DiffSignificant, 1},
+		{EventAdded, DiffSignificant, 0},
+		{EventRemoved, DiffSignificant, 0},
+	}
+	for i, e := range expected {
+		ed := d.Events[i]
+		if ed.Change != e.change || ed.Significance != e.significance || len(ed.Fields) != e.fields {
+			t.Errorf("event %d: expected %s %s with %d fields but got %+v", i, e.change, e.significance, e.fields, ed)
+		}
+	}
+	if d.Events[0].QueryKey != "7,140453136,140453136,A,T" {
+		t.Errorf("unexpected query key %q", d.Events[0].QueryKey)
+	}
+
+	var tsv bytes.Buffer
+	if err := d.WriteTSV(&tsv); err != nil {
+		t.Fatalf("WriteTSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(tsv.String()), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected header and 6 rows but got %d lines:\n%s", len(lines), tsv.String())
+	}
+	if lines[3] != "s1\t12,25398284,25398284,C,T\tCHANGED\tSIGNIFICANT\tHgvspShort\tp.G12D\tp.G13D" {
+		t.Errorf("unexpected TSV row %q", lines[3])
+	}
+
+	var js bytes.Buffer
+	if err := d.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded AnnotationDiff
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed to decode JSON diff: %v", err)
+	}
+	if len(decoded.Events) != 5 || decoded.SignificantEvents != 2 {
+		t.Errorf("unexpected decoded diff: %+v", decoded)
+	}
+}
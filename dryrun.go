@@ -0,0 +1,129 @@
+package genome_nexus_annotator_go
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// redactedToken replaces the Genome Nexus token in previewed requests.
+const redactedToken = "REDACTED"
+
+// DryRunPreview is the Genome Nexus request an annotation would have sent.
+type DryRunPreview struct {
+	// Requests holds one request per batch, in the order they would be sent.
+	Requests []PreviewRequest `json:"requests"`
+	// EventIndexes maps each genomic location key to the indices of the
+	// events sharing it.
+	EventIndexes map[string][]int `json:"eventIndexes"`
+}
+
+// PreviewRequest is a single batched request to Genome Nexus, as built by the
+// Genome Nexus client. The token in URL is redacted.
+type PreviewRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body"`
+	// QueryKeys holds the genomic location key of each location in Body, in order.
+	QueryKeys []string `json:"queryKeys"`
+}
+
+// DryRunTempoMessageEvents prepares the Genome Nexus request that
+// AnnotateTempoMessageEvents would send for tm without sending it. The report
+// holds the request in DryRun and tm is left untouched.
+func (gn GNAnnotatorService) DryRunTempoMessageEvents(isoformOverrideSource string, tm *tt.TempoMessage) (*AnnotationReport, error) {
+	gn.dryRun = true
+	return gn.AnnotateTempoMessageEvents(isoformOverrideSource, tm)
+}
+
+// dryRunReport completes report with the request that would have been sent
+// for req. Events that would have been sent are reported as DRY_RUN.
+func (gn GNAnnotatorService) dryRunReport(
+	isoformOverrideSource string,
+	req annotationRequest,
+	report *AnnotationReport,
+) (*AnnotationReport, error) {
+	preview, err := gn.previewRequest(isoformOverrideSource, req)
+	report.DryRun = preview
+	for key, indices := range req.recordIndices {
+		for _, idx := range indices {
+			report.Statuses[idx] = AnnotationStatus{
+				Code:     StatusDryRun,
+				Reason:   "Genome Nexus request not sent",
+				QueryKey: key,
+			}
+		}
+	}
+	report.tally()
+	return report, err
+}
+
+// errRequestCaptured is returned by captureTransport instead of a response.
+var errRequestCaptured = errors.New("dry run: request not sent")
+
+// captureTransport records the requests of the Genome Nexus client instead of
+// sending them.
+type captureTransport struct {
+	mu       sync.Mutex
+	requests []PreviewRequest
+}
+
+func (t *captureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		r.Body.Close()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests = append(t.requests, PreviewRequest{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Header: r.Header.Clone(),
+		Body:   bytes.TrimSpace(body),
+	})
+	return nil, errRequestCaptured
+}
+
+// previewRequest captures the batched requests getVariantAnnotations would
+// send for req, running it with a client that does not send them.
+func (gn GNAnnotatorService) previewRequest(isoformOverrideSource string, req annotationRequest) (*DryRunPreview, error) {
+	preview := &DryRunPreview{
+		Requests:     make([]PreviewRequest, 0),
+		EventIndexes: req.recordIndices,
+	}
+
+	capture := &captureTransport{}
+	cfg := *gn.client.GetConfig()
+	cfg.HTTPClient = &http.Client{Transport: capture}
+	pgn := gn
+	pgn.client = gnapi.NewAPIClient(&cfg)
+
+	for start := 0; start < len(req.uniqueGenomicLocations); start += gn.batchSize {
+		batch := req.uniqueGenomicLocations[start:min(start+gn.batchSize, len(req.uniqueGenomicLocations))]
+		sent := len(capture.requests)
+		_, err := pgn.getVariantAnnotations(isoformOverrideSource, batch)
+		if len(capture.requests) == sent {
+			return preview, fmt.Errorf("failed to build the Genome Nexus request: %v", err)
+		}
+		request := capture.requests[sent]
+		request.URL = gn.redactTokenString(request.URL)
+		request.QueryKeys = make([]string, len(batch))
+		for i, gl := range batch {
+			request.QueryKeys[i] = buildGenomicLocationKey(gl)
+		}
+		preview.Requests = append(preview.Requests, request)
+	}
+	return preview, nil
+}
@@ -0,0 +1,114 @@
+package genome_nexus_annotator_go
+
+import (
+	"slices"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// Enrichment is an optional annotation requested with WithEnrichments. The
+// Event has no fields for these, so they are returned alongside the events in
+// AnnotationReport.Enrichments.
+type Enrichment string
+
+const (
+	// EnrichmentHotspots resolves the recurrent mutation hotspots of the
+	// canonical transcript the variant falls in.
+	EnrichmentHotspots Enrichment = "hotspots"
+	// EnrichmentClinvar resolves the ClinVar record of the variant.
+	EnrichmentClinvar Enrichment = "clinvar"
+	// EnrichmentOncokb resolves the OncoKB oncogenicity and therapeutic levels
+	// of the variant. Genome Nexus only returns them when given an OncoKB
+	// token, see WithOncokbToken.
+	EnrichmentOncokb Enrichment = "oncokb"
+	// EnrichmentNucleotideContext resolves the trinucleotide context and SBS96
+	// channel of SNVs.
+	EnrichmentNucleotideContext Enrichment = "nucleotide_context"
+	// EnrichmentProtein resolves the protein domains and PTM sites around the
+	// protein position of the canonical transcript.
+	EnrichmentProtein Enrichment = "protein"
+	// EnrichmentSignal resolves the SIGNAL germline frequencies of the variant
+	// in cancer patients.
+	EnrichmentSignal Enrichment = "signal"
+	// EnrichmentGnomad resolves the gnomAD allele frequencies, popmax and the
+	// data set they were taken from, see WithGnomadPreference.
+	EnrichmentGnomad Enrichment = "gnomad"
+)
+
+// allEnrichments lists every Enrichment. An enrichment is also requested when
+// its Genome Nexus field is passed to WithEnrichmentFields.
+var allEnrichments = []Enrichment{
+	EnrichmentHotspots,
+	EnrichmentClinvar,
+	EnrichmentOncokb,
+	EnrichmentNucleotideContext,
+	EnrichmentProtein,
+	EnrichmentSignal,
+	EnrichmentGnomad,
+}
+
+// gnField returns the Genome Nexus field requested for the enrichment.
+func (e Enrichment) gnField() string {
+	switch e {
+	case EnrichmentHotspots:
+		return "hotspots"
+	case EnrichmentClinvar:
+		return "clinvar"
+	case EnrichmentOncokb:
+		return "oncokb"
+	case EnrichmentNucleotideContext:
+		return "nucleotide_context"
+	case EnrichmentProtein:
+		return "ptms"
+	case EnrichmentSignal:
+		return "signal"
+	case EnrichmentGnomad:
+		return "my_variant_info"
+	}
+	return ""
+}
+
+// EventEnrichment holds the optional annotations of a single event.
+type EventEnrichment struct {
+	Hotspots *HotspotEnrichment `json:"hotspots,omitempty"`
+	Clinvar  *ClinvarEnrichment `json:"clinvar,omitempty"`
+	Oncokb   *OncokbEnrichment  `json:"oncokb,omitempty"`
+
+	NucleotideContext *NucleotideContextEnrichment `json:"nucleotideContext,omitempty"`
+	Protein           *ProteinEnrichment           `json:"protein,omitempty"`
+	Signal            *SignalEnrichment            `json:"signal,omitempty"`
+	Gnomad            *GnomadEnrichment            `json:"gnomad,omitempty"`
+}
+
+func (gn GNAnnotatorService) hasEnrichment(e Enrichment) bool {
+	return slices.Contains(gn.enrichments, e)
+}
+
+// resolveEnrichment resolves the requested enrichments of an annotated event.
+func (gn GNAnnotatorService) resolveEnrichment(variantAnnotation gnapi.VariantAnnotation, event *tt.Event) *EventEnrichment {
+	canonicalTranscript := getCanonicalTranscript(variantAnnotation)
+	enrichment := &EventEnrichment{}
+	if gn.hasEnrichment(EnrichmentHotspots) {
+		enrichment.Hotspots = resolveHotspots(variantAnnotation, canonicalTranscript)
+	}
+	if gn.hasEnrichment(EnrichmentClinvar) {
+		enrichment.Clinvar = resolveClinvar(variantAnnotation)
+	}
+	if gn.hasEnrichment(EnrichmentOncokb) {
+		enrichment.Oncokb = resolveOncokb(variantAnnotation)
+	}
+	if gn.hasEnrichment(EnrichmentNucleotideContext) {
+		enrichment.NucleotideContext = resolveNucleotideContext(variantAnnotation, event)
+	}
+	if gn.hasEnrichment(EnrichmentProtein) {
+		enrichment.Protein = resolveProtein(variantAnnotation, canonicalTranscript, gn.ptmWindow)
+	}
+	if gn.hasEnrichment(EnrichmentSignal) {
+		enrichment.Signal = resolveSignal(variantAnnotation)
+	}
+	if gn.hasEnrichment(EnrichmentGnomad) {
+		enrichment.Gnomad = resolveGnomad(variantAnnotation, gn.gnomadPreference)
+	}
+	return enrichment
+}
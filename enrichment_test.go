@@ -0,0 +1,352 @@
+package genome_nexus_annotator_go
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+const enrichmentResponsesJSON = "testdata/gn_enrichment_responses.json"
+
+// newFixtureGNServer serves the Genome Nexus responses recorded in
+// enrichmentResponsesJSON and records the fields of every annotation request,
+// returned by the fields function.
+func newFixtureGNServer(t *testing.T) (*httptest.Server, func() []string) {
+	b, err := os.ReadFile(enrichmentResponsesJSON)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", enrichmentResponsesJSON, err)
+	}
+	var recorded []map[string]interface{}
+	if err := json.Unmarshal(b, &recorded); err != nil {
+		t.Fatalf("failed to parse %s: %v", enrichmentResponsesJSON, err)
+	}
+	responses := make(map[string]map[string]interface{}, len(recorded))
+	for _, va := range recorded {
+		responses[va["originalVariantQuery"].(string)] = va
+	}
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		va, ok := responses[buildGenomicLocationKey(gl)]
+		return va, ok
+	})
+
+	var mu sync.Mutex
+	var fields []string
+	recording := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/annotation/genomic" {
+			mu.Lock()
+			for _, v := range r.URL.Query()["fields"] {
+				fields = append(fields, strings.Split(v, ",")...)
+			}
+			mu.Unlock()
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(recording.Close)
+	return recording, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(fields)
+	}
+}
+
+func annotateWithEnrichments(t *testing.T, events []*tt.Event, opts ...Option) (*AnnotationReport, []string) {
+	server, fields := newFixtureGNServer(t)
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	report, err := gn.AnnotateTempoMessageEvents("mskcc", &tt.TempoMessage{Events: events})
+	if err != nil {
+		t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+	}
+	if len(report.Enrichments) != len(events) {
+		t.Fatalf("expected %d enrichments but got %d", len(events), len(report.Enrichments))
+	}
+	return report, fields()
+}
+
+func TestHotspotEnrichment(t *testing.T) {
+	events := []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+		newTestEvent("12", "25398284", "25398284", "C", "T"),
+		newTestEvent("1", "11181327", "11181327", "C", "T"),
+	}
+	report, fields := annotateWithEnrichments(t, events, WithEnrichments(EnrichmentHotspots))
+	if !slices.Contains(fields, "hotspots") || !slices.Contains(fields, "annotation_summary") {
+		t.Errorf("expected hotspots to be requested with the annotation summary but got %v", fields)
+	}
+
+	braf := report.Enrichments[0].Hotspots
+	if !braf.IsHotspot || !braf.HasType(HotspotTypeSingleResidue) || !braf.HasType(HotspotType3D) {
+		t.Errorf("expected BRAF V600E to be a single residue and 3d hotspot but got %+v", braf)
+	}
+	kras := report.Enrichments[1].Hotspots
+	if len(kras.Hotspots) != 1 || kras.Hotspots[0].TranscriptId != "ENST00000256078" || kras.Hotspots[0].TumorCount != 2175 {
+		t.Errorf("expected only the canonical KRAS G12 hotspot but got %+v", kras)
+	}
+	if mtor := report.Enrichments[2].Hotspots; mtor.IsHotspot || mtor.HasType(HotspotTypeSingleResidue) {
+		t.Errorf("expected MTOR S2215Y not to be a hotspot but got %+v", mtor)
+	}
+}
+
+func TestEnrichmentsNotRequested(t *testing.T) {
+	server, fields := newFixtureGNServer(t)
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	report, err := gn.AnnotateTempoMessageEvents("mskcc", &tt.TempoMessage{Events: []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+	}})
+	if err != nil {
+		t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+	}
+	if report.Enrichments != nil || slices.Contains(fields(), "hotspots") {
+		t.Errorf("expected no enrichments without WithEnrichments but got %v requesting %v", report.Enrichments, fields())
+	}
+}
+
+func TestClinvarEnrichment(t *testing.T) {
+	events := []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+		newTestEvent("22", "29121087", "29121087", "A", "G"),
+		newTestEvent("1", "11181327", "11181327", "C", "T"),
+	}
+	report, fields := annotateWithEnrichments(t, events, WithEnrichments(EnrichmentClinvar))
+	if !slices.Contains(fields, "clinvar") || slices.Contains(fields, "hotspots") {
+		t.Errorf("expected only clinvar to be requested but got %v", fields)
+	}
+
+	braf := report.Enrichments[0].Clinvar
+	if braf == nil || braf.ClinvarId != 13961 || !braf.IsPathogenic() || braf.Conflicting {
+		t.Errorf("expected BRAF V600E to be pathogenic in ClinVar but got %+v", braf)
+	}
+	chek2 := report.Enrichments[1].Clinvar
+	if chek2 == nil || !chek2.Conflicting || chek2.IsPathogenic() || chek2.IsBenign() {
+		t.Fatalf("expected CHEK2 I157T to have conflicting interpretations but got %+v", chek2)
+	}
+	expected := []ClinvarInterpretation{{"Pathogenic", 3}, {"Likely_pathogenic", 2}, {"Uncertain_significance", 9}}
+	if !slices.Equal(chek2.ConflictingInterpretations, expected) {
+		t.Errorf("expected conflicting interpretations %v but got %v", expected, chek2.ConflictingInterpretations)
+	}
+	if report.Enrichments[2].Clinvar != nil {
+		t.Errorf("expected no ClinVar record for MTOR S2215Y but got %+v", report.Enrichments[2].Clinvar)
+	}
+}
+
+func TestOncokbEnrichment(t *testing.T) {
+	server, fields := newFixtureGNServer(t)
+	tokens := make([]string, 0)
+	recording := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/annotation/genomic" {
+			tokens = append(tokens, r.URL.Query().Get("token"))
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer recording.Close()
+
+	tumorTypes := map[string]string{"s1": "MEL", "s2": "Ovarian Cancer", "s3": "COADREAD"}
+	gn, err := NewGNAnnotatorService(context.Background(), `{"source1":"gn-secret"}`, recording.URL,
+		WithOncokbToken("oncokb-secret"),
+		WithTumorType(func(tm *tt.TempoMessage) string { return tumorTypes[tm.CmoSampleId] }),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	tms := []*tt.TempoMessage{
+		{CmoSampleId: "s1", Events: []*tt.Event{newTestEvent("7", "140453136", "140453136", "A", "T")}},
+		{CmoSampleId: "s2", Events: []*tt.Event{newTestEvent("7", "140453136", "140453136", "A", "T")}},
+		{CmoSampleId: "s3", Events: []*tt.Event{newTestEvent("12", "25398284", "25398284", "C", "T")}},
+		{CmoSampleId: "s4", Events: []*tt.Event{newTestEvent("1", "11181327", "11181327", "C", "T")}},
+	}
+	mr, err := gn.AnnotateTempoMessages("mskcc", tms)
+	if err != nil {
+		t.Fatalf("AnnotateTempoMessages failed: %v", err)
+	}
+	if !slices.Contains(fields(), "oncokb") {
+		t.Errorf("expected oncokb to be requested but got %v", fields())
+	}
+	if len(tokens) != 1 || tokens[0] != `{"oncokb":"oncokb-secret","source1":"gn-secret"}` {
+		t.Errorf("expected the OncoKB token to be added to the token map but got %v", tokens)
+	}
+
+	expected := []struct {
+		sensitive, resistance string
+	}{
+		{"LEVEL_1", ""},
+		{"LEVEL_2", ""},
+		{"", "LEVEL_R1"},
+	}
+	for i, e := range expected {
+		oncokb := mr.Reports[i].Enrichments[0].Oncokb
+		if !oncokb.IsOncogenic() || oncokb.TumorType != tumorTypes[tms[i].CmoSampleId] {
+			t.Errorf("%s: expected an oncogenic variant in %s but got %+v", tms[i].CmoSampleId, tumorTypes[tms[i].CmoSampleId], oncokb)
+			continue
+		}
+		if oncokb.TumorTypeSensitiveLevel != e.sensitive || oncokb.TumorTypeResistanceLevel != e.resistance {
+			t.Errorf("%s: expected levels %q and %q but got %q and %q", tms[i].CmoSampleId,
+				e.sensitive, e.resistance, oncokb.TumorTypeSensitiveLevel, oncokb.TumorTypeResistanceLevel)
+		}
+	}
+	if braf := mr.Reports[0].Enrichments[0].Oncokb; braf.MutationEffect != "Gain-of-function" || braf.HighestSensitiveLevel != "LEVEL_1" {
+		t.Errorf("unexpected BRAF V600E OncoKB annotation %+v", braf)
+	}
+	if mtor := mr.Reports[3].Enrichments[0].Oncokb; mtor != nil {
+		t.Errorf("expected no OncoKB annotation for MTOR S2215Y but got %+v", mtor)
+	}
+}
+
+func TestOncokbTokenIsNotLeaked(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	if _, err := NewGNAnnotatorService(context.Background(), "gn-secret", server.URL, WithOncokbToken("oncokb-secret")); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("expected an error not quoting the tokens for a token that is not a JSON map but got %v", err)
+	}
+
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL, WithOncokbToken("oncokb-secret"))
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	report, err := gn.AnnotateTempoMessageEvents("mskcc", &tt.TempoMessage{Events: []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+	}})
+	if err == nil {
+		t.Fatal("expected the request to an unreachable server to fail")
+	}
+	b, _ := json.Marshal(report)
+	for _, s := range []string{err.Error(), string(b)} {
+		if strings.Contains(s, "oncokb-secret") {
+			t.Errorf("the OncoKB token was leaked: %s", s)
+		}
+	}
+}
+
+func TestNucleotideContextEnrichment(t *testing.T) {
+	events := []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+		newTestEvent("12", "25398284", "25398284", "C", "T"),
+		newTestEvent("22", "29121087", "29121087", "A", "G"),
+	}
+	report, fields := annotateWithEnrichments(t, events, WithEnrichments(EnrichmentNucleotideContext))
+	if !slices.Contains(fields, "nucleotide_context") {
+		t.Errorf("expected nucleotide_context to be requested but got %v", fields)
+	}
+	expected := []NucleotideContextEnrichment{
+		{Context: "CAC", SBS96: "G[T>A]G"},
+		{Context: "ACC", SBS96: "A[C>T]C"},
+		{Context: "AAT", SBS96: "A[T>C]T"},
+	}
+	for i, e := range expected {
+		if got := report.Enrichments[i].NucleotideContext; got == nil || *got != e {
+			t.Errorf("%s: expected %+v but got %+v", report.Statuses[i].QueryKey, e, got)
+		}
+	}
+}
+
+func TestProteinEnrichment(t *testing.T) {
+	events := []*tt.Event{
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+		newTestEvent("12", "25398284", "25398284", "C", "T"),
+	}
+	report, fields := annotateWithEnrichments(t, events, WithEnrichments(EnrichmentProtein), WithPtmWindow(2))
+	if !slices.Contains(fields, "ptms") {
+		t.Errorf("expected ptms to be requested but got %v", fields)
+	}
+	// the event keeps the VEP domains as Genome Nexus returned them
+	if !strings.HasPrefix(events[0].VepDomains, "map[") || !strings.Contains(events[0].VepDomains, "PF07714") {
+		t.Errorf("unexpected VepDomains %q", events[0].VepDomains)
+	}
+
+	braf := report.Enrichments[0].Protein
+	if braf == nil || braf.ProteinStart != 600 || braf.ProteinEnd != 600 {
+		t.Fatalf("expected the BRAF V600E protein position but got %+v", braf)
+	}
+	if pfam := braf.PfamDomains(); len(pfam) != 1 || pfam[0] != (ProteinDomain{Source: "Pfam", Id: "PF07714"}) {
+		t.Errorf("expected the BRAF kinase domain but got %v", pfam)
+	}
+	if len(braf.Ptms) != 2 || braf.Ptms[0].Position != 599 || braf.Ptms[1].Position != 602 || braf.Ptms[0].Type != "Phosphorylation" {
+		t.Errorf("expected the canonical PTM sites within 2 residues but got %+v", braf.Ptms)
+	}
+	kras := report.Enrichments[1].Protein
+	if len(kras.Domains) != 2 || len(kras.Ptms) != 0 {
+		t.Errorf("expected the deduplicated KRAS domains and no PTM sites but got %+v", kras)
+	}
+}
+
+func TestSignalEnrichment(t *testing.T) {
+	events := []*tt.Event{
+		newTestEvent("22", "29121087", "29121087", "A", "G"),
+		newTestEvent("7", "140453136", "140453136", "A", "T"),
+	}
+	report, fields := annotateWithEnrichments(t, events, WithEnrichmentFields("annotation_summary", "signal"))
+	if !slices.Contains(fields, "signal") {
+		t.Errorf("expected signal to be requested but got %v", fields)
+	}
+
+	germline := report.Enrichments[0].Signal.Germline()
+	if germline == nil || germline.GermlineFrequency == nil || *germline.GermlineFrequency != 0.0052 ||
+		germline.BiallelicRatio == nil || *germline.BiallelicRatio != 0.21 || germline.GermlineHomozygousCount != 1 {
+		t.Fatalf("unexpected CHEK2 I157T germline SIGNAL record %+v", germline)
+	}
+	breast := SignalTumorTypeCount{TumorType: "Breast Carcinoma", TumorTypeCount: 2500, VariantCount: 18, BiallelicCount: 4}
+	if len(germline.TumorTypes) != 2 || germline.TumorTypes[0] != breast || breast.Frequency() != 0.0072 {
+		t.Errorf("unexpected CHEK2 I157T tumor type counts %+v", germline.TumorTypes)
+	}
+	if len(report.Enrichments[0].Signal.Records) != 2 {
+		t.Errorf("expected germline and somatic records but got %+v", report.Enrichments[0].Signal.Records)
+	}
+	if report.Enrichments[1].Signal != nil || report.Enrichments[1].Hotspots != nil {
+		t.Errorf("expected only SIGNAL to be resolved and BRAF V600E not to be in SIGNAL but got %+v", report.Enrichments[1])
+	}
+}
+
+func TestGnomadEnrichment(t *testing.T) {
+	newEvents := func() []*tt.Event {
+		return []*tt.Event{
+			newTestEvent("22", "29121087", "29121087", "A", "G"),
+			newTestEvent("1", "11181327", "11181327", "C", "T"),
+		}
+	}
+
+	events := newEvents()
+	report, _ := annotateWithEnrichments(t, events, WithEnrichments(EnrichmentGnomad))
+	if events[0].GnomadAf != "" || events[1].GnomadAf != "4e-05" || events[1].GnomadSasAf != "0.0002" {
+		t.Errorf("expected only exome frequencies by default but got %q and %q", events[0].GnomadAf, events[1].GnomadAf)
+	}
+	if report.Enrichments[0].Gnomad != nil {
+		t.Errorf("expected no exome frequencies for CHEK2 I157T but got %+v", report.Enrichments[0].Gnomad)
+	}
+
+	events = newEvents()
+	report, _ = annotateWithEnrichments(t, events, WithEnrichments(EnrichmentGnomad), WithGnomadPreference(GnomadSourceExome, GnomadSourceGenome))
+	if events[0].GnomadAf != "0.0021" || events[0].GnomadFinAf != "0.0102" || events[1].GnomadAf != "4e-05" {
+		t.Errorf("expected genome frequencies when exome ones are missing but got %q and %q", events[0].GnomadAf, events[1].GnomadAf)
+	}
+	chek2 := report.Enrichments[0].Gnomad
+	if chek2 == nil || chek2.Sources["af"] != GnomadSourceGenome || chek2.Popmax != 0.0031 || chek2.PopmaxPopulation != "nfe" || chek2.PopmaxSource != GnomadSourceGenome {
+		t.Errorf("expected the genome NFE popmax for CHEK2 I157T, leaving out FIN, but got %+v", chek2)
+	}
+
+	events = newEvents()
+	report, _ = annotateWithEnrichments(t, events, WithEnrichments(EnrichmentGnomad), WithGnomadPreference(GnomadSourceMax))
+	mtor := report.Enrichments[1].Gnomad
+	if mtor.AF != 0.00006 || mtor.Sources["af"] != GnomadSourceGenome || mtor.PopulationAFs["sas"] != 0.0002 || mtor.Sources["sas"] != GnomadSourceExome {
+		t.Errorf("expected the higher frequency of each population but got %+v", mtor)
+	}
+	if mtor.Popmax != 0.0004 || mtor.PopmaxPopulation != "amr" || mtor.PopmaxSource != GnomadSourceGenome {
+		t.Errorf("expected the genome AMR popmax but got %+v", mtor)
+	}
+	if events[1].GnomadAf != "6e-05" || events[1].GnomadSasAf != "0.0002" {
+		t.Errorf("expected the event to hold the highest frequencies but got %q and %q", events[1].GnomadAf, events[1].GnomadSasAf)
+	}
+}
@@ -0,0 +1,192 @@
+package genome_nexus_annotator_go
+
+import (
+	"strconv"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// FilterVerdict classifies an annotated event of a tumor-only sample.
+type FilterVerdict string
+
+const (
+	FilterSomatic        FilterVerdict = "SOMATIC"
+	FilterLikelyGermline FilterVerdict = "LIKELY_GERMLINE"
+	FilterArtifact       FilterVerdict = "ARTIFACT"
+)
+
+// Filter reasons, the first one following the common_variant FILTER of vcf2maf.
+const (
+	ReasonCommonVariant       = "common_variant"
+	ReasonClinvarBenign       = "clinvar_benign"
+	ReasonDbsnpGermlineVAF    = "dbsnp_germline_vaf"
+	ReasonLowDepth            = "low_depth"
+	ReasonLowAltCount         = "low_alt_count"
+	ReasonLowVAF              = "low_vaf"
+	ReasonNormalContamination = "normal_contamination"
+)
+
+// GermlineMutationStatus is the Mutation_Status cBioPortal excludes as germline.
+const GermlineMutationStatus = "Germline"
+
+// FilterResult is the verdict of a filter on an event and the reasons for it.
+type FilterResult struct {
+	Verdict FilterVerdict `json:"verdict"`
+	Reasons []string      `json:"reasons,omitempty"`
+}
+
+// AnnotationFilter is a filter run on every successfully annotated event,
+// see WithFilters. Filtered events are marked in AnnotationReport.Filters
+// rather than removed.
+type AnnotationFilter interface {
+	// FilterEvent returns the verdict on an annotated event, or nil when the
+	// filter does not apply to it. enrichment is nil unless enrichments were
+	// requested.
+	FilterEvent(e *tt.Event, enrichment *EventEnrichment) *FilterResult
+}
+
+// filterEvent runs the filters set with WithFilters on an annotated event. The
+// first verdict other than FilterSomatic wins.
+func (gn GNAnnotatorService) filterEvent(e *tt.Event, enrichment *EventEnrichment) *FilterResult {
+	var result *FilterResult
+	for _, f := range gn.filters {
+		r := f.FilterEvent(e, enrichment)
+		if r == nil {
+			continue
+		}
+		if r.Verdict != FilterSomatic {
+			return r
+		}
+		if result == nil {
+			result = r
+		}
+	}
+	return result
+}
+
+// GermlineFilter flags the common germline variants and the artifacts among
+// the annotated events of a tumor-only sample. A zero threshold disables its
+// check.
+type GermlineFilter struct {
+	// MaxPopulationAF is the highest gnomAD allele frequency, across all
+	// populations, of a somatic variant.
+	MaxPopulationAF float64
+	// ClinvarBenign flags the variants ClinVar classifies as benign or likely
+	// benign as germline. It needs EnrichmentClinvar.
+	ClinvarBenign bool
+	// DbsnpGermlineVAF flags variants in dbSNP and gnomAD with at least this
+	// variant allele frequency as germline. Hotspots, ClinVar pathogenic and
+	// OncoKB oncogenic variants are left out, since many clonal somatic
+	// drivers, e.g. BRAF V600E, have a dbSNP id; telling them apart needs the
+	// corresponding enrichments.
+	DbsnpGermlineVAF float64
+	// MinDepth, MinAltCount and MinVAF flag the events with fewer tumor reads,
+	// fewer alternate reads or a lower variant allele frequency as artifacts.
+	MinDepth    int
+	MinAltCount int
+	MinVAF      float64
+	// SetMutationStatus sets the Mutation_Status of likely germline events to
+	// GermlineMutationStatus, so cBioPortal leaves them out.
+	SetMutationStatus bool
+}
+
+// DefaultGermlineFilter returns the filter flagging as germline the variants
+// more frequent than 0.04% in a gnomAD population, as vcf2maf, or benign in
+// ClinVar, and as artifacts the events with fewer than 8 tumor reads, 3
+// alternate reads or a variant allele frequency under 2%. The dbSNP rule is
+// off.
+func DefaultGermlineFilter() *GermlineFilter {
+	return &GermlineFilter{
+		MaxPopulationAF:   0.0004,
+		ClinvarBenign:     true,
+		MinDepth:          8,
+		MinAltCount:       3,
+		MinVAF:            0.02,
+		SetMutationStatus: true,
+	}
+}
+
+// Apply filters the successfully annotated events of tm, whose annotation
+// report is report, and records the results in report.Filters. The filter can
+// also be run during annotation, see WithFilters. Nothing is filtered without
+// a report, since it tells which events were annotated.
+//
+// The reasons are only kept in the report: tempo events have no FILTER
+// field, so the only mark left on an event is its Mutation_Status, see
+// SetMutationStatus.
+func (f *GermlineFilter) Apply(tm *tt.TempoMessage, report *AnnotationReport) {
+	if report == nil {
+		return
+	}
+	report.Filters = make([]*FilterResult, len(tm.Events))
+	for i, e := range tm.Events {
+		if i >= len(report.Statuses) || !report.Statuses[i].IsSuccess() {
+			continue
+		}
+		var enrichment *EventEnrichment
+		if i < len(report.Enrichments) {
+			enrichment = report.Enrichments[i]
+		}
+		report.Filters[i] = f.FilterEvent(e, enrichment)
+	}
+}
+
+// FilterEvent implements AnnotationFilter, artifacts taking precedence over
+// germline variants.
+func (f *GermlineFilter) FilterEvent(e *tt.Event, enrichment *EventEnrichment) *FilterResult {
+	result := f.filter(e, enrichment)
+	if f.SetMutationStatus && result.Verdict == FilterLikelyGermline {
+		e.MutationStatus = GermlineMutationStatus
+	}
+	return result
+}
+
+func (f *GermlineFilter) filter(e *tt.Event, enrichment *EventEnrichment) *FilterResult {
+	tumor := ComputeEventMetrics(e).Tumor
+	artifact := (&ReadCountFilter{MinDepth: f.MinDepth, MinAltCount: f.MinAltCount, MinVAF: f.MinVAF}).FilterEvent(e, enrichment)
+	if artifact != nil {
+		return artifact
+	}
+
+	germline := &FilterResult{Verdict: FilterLikelyGermline}
+	if f.MaxPopulationAF > 0 && maxPopulationAF(e) > f.MaxPopulationAF {
+		germline.Reasons = append(germline.Reasons, ReasonCommonVariant)
+	}
+	if f.ClinvarBenign && enrichment != nil && enrichment.Clinvar.IsBenign() {
+		germline.Reasons = append(germline.Reasons, ReasonClinvarBenign)
+	}
+	if f.DbsnpGermlineVAF > 0 && tumor.Valid && tumor.VAF() >= f.DbsnpGermlineVAF &&
+		strings.HasPrefix(e.DbsnpRs, "rs") && maxPopulationAF(e) > 0 && !isKnownDriver(enrichment) {
+		germline.Reasons = append(germline.Reasons, ReasonDbsnpGermlineVAF)
+	}
+	if len(germline.Reasons) > 0 {
+		return germline
+	}
+	return &FilterResult{Verdict: FilterSomatic}
+}
+
+// isKnownDriver returns whether the enrichments mark the variant as a hotspot,
+// pathogenic in ClinVar or oncogenic in OncoKB.
+func isKnownDriver(enrichment *EventEnrichment) bool {
+	if enrichment == nil {
+		return false
+	}
+	return (enrichment.Hotspots != nil && enrichment.Hotspots.IsHotspot) ||
+		enrichment.Clinvar.IsPathogenic() || enrichment.Oncokb.IsOncogenic()
+}
+
+// maxPopulationAF returns the highest gnomAD allele frequency of the event
+// across all populations.
+func maxPopulationAF(e *tt.Event) float64 {
+	highest := 0.0
+	for _, v := range []string{
+		e.GnomadAf, e.GnomadAfrAf, e.GnomadAmrAf, e.GnomadAsjAf, e.GnomadEasAf,
+		e.GnomadFinAf, e.GnomadNfeAf, e.GnomadOthAf, e.GnomadSasAf,
+	} {
+		if af, err := strconv.ParseFloat(v, 64); err == nil && af > highest {
+			highest = af
+		}
+	}
+	return highest
+}
@@ -0,0 +1,134 @@
+package genome_nexus_annotator_go
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func TestGermlineFilter(t *testing.T) {
+	event := func(altCount, refCount, gnomadAf, dbsnp string) *tt.Event {
+		return &tt.Event{TAltCount: altCount, TRefCount: refCount, GnomadNfeAf: gnomadAf, DbsnpRs: dbsnp, MutationStatus: "Somatic"}
+	}
+	benign := &EventEnrichment{Clinvar: &ClinvarEnrichment{ClinicalSignificance: "Benign/Likely_benign"}}
+	tests := []struct {
+		name       string
+		event      *tt.Event
+		enrichment *EventEnrichment
+		verdict    FilterVerdict
+		reasons    []string
+	}{
+		{"somatic", event("20", "80", "", ""), nil, FilterSomatic, nil},
+		{"rare", event("20", "80", "0.0001", "rs1"), nil, FilterSomatic, nil},
+		{"common", event("20", "80", "0.012", ""), nil, FilterLikelyGermline, []string{ReasonCommonVariant}},
+		{"clinvar benign", event("20", "80", "", ""), benign, FilterLikelyGermline, []string{ReasonClinvarBenign}},
+		{"dbsnp heterozygous", event("48", "52", "0.0002", "rs12345"), nil, FilterSomatic, nil},
+		{"no read counts", event("", "", "0.3", "rs12345"), nil, FilterLikelyGermline, []string{ReasonCommonVariant}},
+		{"low depth", event("2", "3", "0.3", ""), nil, FilterArtifact, []string{ReasonLowDepth, ReasonLowAltCount}},
+		{"low vaf", event("3", "300", "", ""), nil, FilterArtifact, []string{ReasonLowVAF}},
+	}
+
+	tm := &tt.TempoMessage{}
+	report := &AnnotationReport{}
+	for _, tc := range tests {
+		tm.Events = append(tm.Events, tc.event)
+		report.Statuses = append(report.Statuses, AnnotationStatus{Code: StatusSuccess})
+		report.Enrichments = append(report.Enrichments, tc.enrichment)
+	}
+	tm.Events = append(tm.Events, event("20", "80", "0.3", ""))
+	report.Statuses = append(report.Statuses, AnnotationStatus{Code: StatusGNUnsuccessful})
+
+	DefaultGermlineFilter().Apply(tm, report)
+	for i, tc := range tests {
+		result := report.Filters[i]
+		if result.Verdict != tc.verdict || !slices.Equal(result.Reasons, tc.reasons) {
+			t.Errorf("%s: expected %s %v but got %s %v", tc.name, tc.verdict, tc.reasons, result.Verdict, result.Reasons)
+		}
+		if germline := tm.Events[i].MutationStatus == GermlineMutationStatus; germline != (tc.verdict == FilterLikelyGermline) {
+			t.Errorf("%s: unexpected Mutation_Status %q", tc.name, tm.Events[i].MutationStatus)
+		}
+	}
+	if report.Filters[len(tests)] != nil || tm.Events[len(tests)].MutationStatus != "Somatic" {
+		t.Errorf("expected events that were not annotated to be left alone but got %+v", report.Filters[len(tests)])
+	}
+
+	unfiltered := &tt.TempoMessage{Events: []*tt.Event{event("20", "80", "0.3", "")}}
+	DefaultGermlineFilter().Apply(unfiltered, nil)
+	if unfiltered.Events[0].MutationStatus != "Somatic" {
+		t.Errorf("expected events to be left alone without a report but got %q", unfiltered.Events[0].MutationStatus)
+	}
+}
+
+func TestGermlineFilterDbsnp(t *testing.T) {
+	event := func(gnomadAf string) *tt.Event {
+		return &tt.Event{TAltCount: "48", TRefCount: "52", GnomadNfeAf: gnomadAf, DbsnpRs: "rs113488022"}
+	}
+	tests := []struct {
+		name       string
+		event      *tt.Event
+		enrichment *EventEnrichment
+		verdict    FilterVerdict
+	}{
+		{"rare germline", event("0.0002"), nil, FilterLikelyGermline},
+		{"not in gnomAD", event(""), nil, FilterSomatic},
+		{"hotspot", event("0.0002"), &EventEnrichment{Hotspots: &HotspotEnrichment{IsHotspot: true}}, FilterSomatic},
+		{"clinvar pathogenic", event("0.0002"), &EventEnrichment{Clinvar: &ClinvarEnrichment{ClinicalSignificance: "Pathogenic"}}, FilterSomatic},
+		{"oncogenic", event("0.0002"), &EventEnrichment{Oncokb: &OncokbEnrichment{Oncogenic: "Oncogenic"}}, FilterSomatic},
+	}
+	f := DefaultGermlineFilter()
+	f.DbsnpGermlineVAF = 0.35
+	for _, tc := range tests {
+		if result := f.FilterEvent(tc.event, tc.enrichment); result.Verdict != tc.verdict {
+			t.Errorf("%s: expected %s but got %s %v", tc.name, tc.verdict, result.Verdict, result.Reasons)
+		}
+	}
+}
+
+func TestAnnotateWithFilters(t *testing.T) {
+	withCounts := func(e *tt.Event, tRef, tAlt, nRef, nAlt string) *tt.Event {
+		e.TRefCount, e.TAltCount, e.NRefCount, e.NAltCount = tRef, tAlt, nRef, nAlt
+		return e
+	}
+	tms := []*tt.TempoMessage{
+		{CmoSampleId: "s1", Events: []*tt.Event{
+			withCounts(newTestEvent("7", "140453136", "140453136", "A", "T"), "60", "40", "50", "0"),
+			withCounts(newTestEvent("12", "25398284", "25398284", "C", "T"), "60", "40", "45", "5"),
+		}},
+		{CmoSampleId: "s2", Events: []*tt.Event{
+			withCounts(newTestEvent("22", "29121087", "29121087", "A", "G"), "3", "1", "", ""),
+			newTestEvent("3", "100", "100", "A", "G"),
+		}},
+	}
+	server, _ := newFixtureGNServer(t)
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL,
+		WithFilters(&ReadCountFilter{MinDepth: 10, MinAltCount: 3, MaxNormalVAF: 0.05}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	mr, _ := gn.AnnotateTempoMessages("mskcc", tms)
+
+	expected := [][][]string{
+		{nil, {ReasonNormalContamination}},
+		{{ReasonLowDepth, ReasonLowAltCount}, nil},
+	}
+	for i, report := range mr.Reports {
+		for j, reasons := range expected[i] {
+			result := report.Filters[j]
+			if reasons == nil {
+				if result != nil {
+					t.Errorf("%s event %d: expected no filter but got %+v", tms[i].CmoSampleId, j, result)
+				}
+				continue
+			}
+			if result == nil || result.Verdict != FilterArtifact || !slices.Equal(result.Reasons, reasons) {
+				t.Errorf("%s event %d: expected an artifact for %v but got %+v", tms[i].CmoSampleId, j, reasons, result)
+			}
+		}
+	}
+	if tms[1].Events[0].HugoSymbol != "CHEK2" {
+		t.Errorf("expected filtered events to be annotated, not removed, but got %+v", tms[1].Events[0])
+	}
+}
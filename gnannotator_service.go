@@ -2,25 +2,63 @@ package genome_nexus_annotator_go
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
 	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
 )
 
+const (
+	defaultBatchSize    int           = 200
+	defaultRetryBackoff time.Duration = time.Second
+)
+
 type GNAnnotator interface {
 	GetGenomeNexusInfo() (*gnapi.AggregateSourceInfo, error)
-	AnnotateTempoMessageEvents(isoformOverrideSource string, tm *tt.TempoMessage) error
+	AnnotateTempoMessageEvents(isoformOverrideSource string, tm *tt.TempoMessage) (*AnnotationReport, error)
+	AnnotateTempoMessageEventsContext(ctx context.Context, isoformOverrideSource string, tm *tt.TempoMessage) (*AnnotationReport, error)
+	AnnotateTempoMessages(isoformOverrideSource string, tms []*tt.TempoMessage) (*MessagesReport, error)
+	DryRunTempoMessageEvents(isoformOverrideSource string, tm *tt.TempoMessage) (*AnnotationReport, error)
+	AnnotateStream(ctx context.Context, isoformOverrideSource string, in <-chan *tt.TempoMessage) <-chan StreamResult
+	ReannotateTempoMessageEvents(isoformOverrideSource string, tm *tt.TempoMessage, recordedVersion string) (*AnnotationReport, error)
+	CurrentAnnotationVersion() (string, error)
+	RefreshGenomeNexusInfo() (*gnapi.AggregateSourceInfo, error)
 }
 
 type GNAnnotatorService struct {
 	client         *gnapi.APIClient
 	ctxAccessToken context.Context
 	token          string
+	infoCache      *infoCache
+
+	proteinPositionRange bool
+	batchSize            int
+	maxRetries           int
+	retryBackoff         time.Duration
+	reference            ReferenceGenome
+	referenceCheckMode   ReferenceCheckMode
+	streamMaxEvents      int
+	streamWindow         time.Duration
+	dryRun               bool
+	fields               []string
+	concurrency          int
+	stripMatchingBases   string
+	cache                AnnotationCache
+	enrichments          []Enrichment
+	oncokbToken          string
+	tumorType            TumorTypeFunc
+	ptmWindow            int
+	gnomadPreference     []GnomadSource
+	filters              []AnnotationFilter
 }
 
-func NewGNAnnotatorService(ctx context.Context, token, gnURL string) (GNAnnotator, error) {
+func NewGNAnnotatorService(ctx context.Context, token, gnURL string, opts ...Option) (GNAnnotator, error) {
 	if len(gnURL) == 0 {
 		return nil, fmt.Errorf("gnURL: %q needs to be valid", gnURL)
 	}
@@ -32,7 +70,36 @@ func NewGNAnnotatorService(ctx context.Context, token, gnURL string) (GNAnnotato
 		},
 	}
 	client := gnapi.NewAPIClient(cfg)
-	return GNAnnotatorService{client: client, ctxAccessToken: ctx, token: token}, nil
+	gn := GNAnnotatorService{
+		client:          client,
+		ctxAccessToken:  ctx,
+		token:           token,
+		infoCache:       &infoCache{},
+		batchSize:       defaultBatchSize,
+		retryBackoff:    defaultRetryBackoff,
+		streamMaxEvents: defaultBatchSize,
+		streamWindow:    defaultStreamWindow,
+		concurrency:     1,
+		ptmWindow:       defaultPtmWindow,
+
+		stripMatchingBases: StripMatchingBasesAll,
+	}
+	for _, opt := range opts {
+		opt(&gn)
+	}
+	for _, e := range allEnrichments {
+		if slices.Contains(gn.fields, e.gnField()) && !gn.hasEnrichment(e) {
+			gn.enrichments = append(gn.enrichments, e)
+		}
+	}
+	if gn.oncokbToken != "" {
+		token, err := addOncokbToken(gn.token, gn.oncokbToken)
+		if err != nil {
+			return nil, err
+		}
+		gn.token = token
+	}
+	return gn, nil
 
 }
 
@@ -44,79 +111,284 @@ func (gn GNAnnotatorService) GetGenomeNexusInfo() (*gnapi.AggregateSourceInfo, e
 	return resp, nil
 }
 
+// AnnotateTempoMessageEvents annotates every event of tm in place and returns a
+// report holding the status of each event, in the same order as tm.Events. The
+// concise form of each status is also written to the event's AnnotationStatus.
+// Events whose batch could not be sent to Genome Nexus are left untouched and
+// the request errors are returned alongside the report.
 func (gn GNAnnotatorService) AnnotateTempoMessageEvents(
 	isoformOverrideSource string,
 	tm *tt.TempoMessage,
-) error {
-	// prepare genomic locations for annotation
-	genomicLocations := make([]gnapi.GenomicLocation, 0)
-	for _, a := range tm.Events {
-		loc := gn.getGenomicLocation(a)
-		genomicLocations = append(genomicLocations, loc)
-	}
+) (*AnnotationReport, error) {
+	report, err := gn.annotateEvents(isoformOverrideSource, tm.Events)
+	gn.applyTumorType(tm, report)
+	return report, err
+}
 
-	variantAnnotations, err := gn.getVariantAnnotations(isoformOverrideSource, genomicLocations)
-	if err != nil {
-		return err
+// AnnotateTempoMessageEventsContext is AnnotateTempoMessageEvents with the
+// requests to Genome Nexus bound to ctx, so they are cancelled with it. Events
+// whose batch was not sent before ctx was done are reported as TRANSPORT_ERROR.
+func (gn GNAnnotatorService) AnnotateTempoMessageEventsContext(
+	ctx context.Context,
+	isoformOverrideSource string,
+	tm *tt.TempoMessage,
+) (*AnnotationReport, error) {
+	cgn := gn
+	cgn.ctxAccessToken = ctx
+	return cgn.AnnotateTempoMessageEvents(isoformOverrideSource, tm)
+}
+
+// annotateEvents annotates events in place, sending each distinct genomic
+// location to Genome Nexus once and fanning the response out to every event
+// sharing that location.
+func (gn GNAnnotatorService) annotateEvents(
+	isoformOverrideSource string,
+	events []*tt.Event,
+) (*AnnotationReport, error) {
+	report := newAnnotationReport(len(events))
+	report.Provenance = gn.getProvenance(isoformOverrideSource)
+	if len(gn.enrichments) > 0 {
+		report.Enrichments = make([]*EventEnrichment, len(events))
+	}
+	if len(gn.filters) > 0 {
+		report.Filters = make([]*FilterResult, len(events))
 	}
 
-	// Build a mapping from genomic location key -> indices of records
-	genomicLocationToRecordIndices := make(map[string][]int)
-	for i, gl := range genomicLocations {
-		key := buildGenomicLocationKey(gl)
-		genomicLocationToRecordIndices[key] = append(genomicLocationToRecordIndices[key], i)
+	req := gn.prepareAnnotationRequest(events)
+	copy(report.Statuses, req.statuses)
+	if gn.dryRun {
+		return gn.dryRunReport(isoformOverrideSource, req, report)
+	}
+	for i, a := range events {
+		if req.explanations[i] != "" {
+			a.GenomicLocationExplanation = req.explanations[i]
+		}
+		if report.Statuses[i].Code != "" {
+			a.AnnotationStatus = report.Statuses[i].String()
+		}
+	}
+	// Map each variant annotation to the correct record(s) by key
+	applyVariantAnnotations := func(variantAnnotations []gnapi.VariantAnnotation) {
+		for _, variantAnnotation := range variantAnnotations {
+			// Prefer the original variant query key when available
+			var key string
+			if variantAnnotation.OriginalVariantQuery != "" {
+				key = variantAnnotation.OriginalVariantQuery
+			} else {
+				continue
+			}
+			if indices, ok := req.recordIndices[key]; ok {
+				for _, idx := range indices {
+					report.Statuses[idx] = gn.mapResponseToEvent(variantAnnotation, req.genomicLocations[idx], events[idx])
+					events[idx].AnnotationStatus = report.Statuses[idx].String()
+					if report.Enrichments != nil && report.Statuses[idx].IsSuccess() {
+						report.Enrichments[idx] = gn.resolveEnrichment(variantAnnotation, events[idx])
+					}
+					if report.Filters != nil && report.Statuses[idx].IsSuccess() {
+						var enrichment *EventEnrichment
+						if report.Enrichments != nil {
+							enrichment = report.Enrichments[idx]
+						}
+						report.Filters[idx] = gn.filterEvent(events[idx], enrichment)
+					}
+				}
+			}
+		}
 	}
 
-	// Track which records were annotated by the response
-	annotated := make([]bool, len(genomicLocations))
+	uncached := req.uniqueGenomicLocations
+	useCache := gn.useCache(report.Provenance.EnrichmentFields)
+	if useCache {
+		uncached = make([]gnapi.GenomicLocation, 0)
+		cached := make([]gnapi.VariantAnnotation, 0)
+		for _, gl := range req.uniqueGenomicLocations {
+			if va, ok := gn.cache.Get(annotationCacheKey(report.Provenance, buildGenomicLocationKey(gl))); ok {
+				cached = append(cached, va)
+				continue
+			}
+			uncached = append(uncached, gl)
+		}
+		report.CacheHits = len(cached)
+		applyVariantAnnotations(cached)
+	}
 
-	// Map each returned variant annotation to the correct record(s) by key
-	for _, variantAnnotation := range variantAnnotations {
-		// Prefer the original variant query key when available
-		var key string
-		if variantAnnotation.OriginalVariantQuery != "" {
-			key = variantAnnotation.OriginalVariantQuery
-		} else {
+	var errs []error
+	for _, result := range gn.fetchBatches(isoformOverrideSource, uncached) {
+		report.Batches = append(report.Batches, result.timing)
+		report.Retries += result.timing.Attempts - 1
+		if result.err != nil {
+			errs = append(errs, result.err)
+			for _, gl := range result.batch {
+				key := buildGenomicLocationKey(gl)
+				for _, idx := range req.recordIndices[key] {
+					report.Statuses[idx] = AnnotationStatus{
+						Code:         StatusTransportError,
+						Reason:       "Genome Nexus request failed",
+						QueryKey:     key,
+						ErrorMessage: result.err.Error(),
+					}
+					events[idx].AnnotationStatus = report.Statuses[idx].String()
+				}
+			}
 			continue
 		}
-		if indices, ok := genomicLocationToRecordIndices[key]; ok {
-			for _, idx := range indices {
-				gn.mapResponseToEvent(variantAnnotation, genomicLocations[idx], tm.Events[idx])
-				annotated[idx] = true
+		applyVariantAnnotations(result.variantAnnotations)
+		if useCache {
+			for _, va := range result.variantAnnotations {
+				if va.OriginalVariantQuery != "" && va.SuccessfullyAnnotated != nil && *va.SuccessfullyAnnotated {
+					gn.cache.Put(annotationCacheKey(report.Provenance, va.OriginalVariantQuery), va)
+				}
 			}
 		}
 	}
 
 	// Any records not annotated by Genome Nexus response should be marked as failure
-	for i := range genomicLocations {
-		if !annotated[i] {
-			tm.Events[i].AnnotationStatus = fmt.Sprintf(
-				"FAILURE: No variant annotation returned for genomicLocation: %s",
-				buildGenomicLocationKey(genomicLocations[i]),
-			)
+	for i := range req.genomicLocations {
+		if report.Statuses[i].Code == "" {
+			report.Statuses[i] = AnnotationStatus{
+				Code:     StatusNoResponse,
+				Reason:   "No variant annotation returned",
+				QueryKey: buildGenomicLocationKey(req.genomicLocations[i]),
+			}
+			events[i].AnnotationStatus = report.Statuses[i].String()
 		}
 	}
-	return nil
+	report.tally()
+	return report, errors.Join(errs...)
 }
 
-func (gn GNAnnotatorService) getGenomicLocation(e *tt.Event) gnapi.GenomicLocation {
-	start, _ := strconv.ParseInt(e.StartPosition, 10, 32)
-	end, _ := strconv.ParseInt(e.EndPosition, 10, 32)
+// annotationRequest is the Genome Nexus request prepared for a set of events,
+// before anything is sent or written to the events.
+type annotationRequest struct {
+	// statuses holds the status of the events left out of the request.
+	statuses []AnnotationStatus
+	// explanations holds the reference check outcome to record on each event.
+	explanations           []string
+	genomicLocations       []gnapi.GenomicLocation
+	uniqueGenomicLocations []gnapi.GenomicLocation
+	recordIndices          map[string][]int
+}
+
+// prepareAnnotationRequest checks the events and builds their genomic
+// locations, leaving invalid events out of the request. Each distinct genomic
+// location is only requested once; recordIndices maps its key to the indices
+// of the events sharing it. events are not modified.
+func (gn GNAnnotatorService) prepareAnnotationRequest(events []*tt.Event) annotationRequest {
+	req := annotationRequest{
+		statuses:               make([]AnnotationStatus, len(events)),
+		explanations:           make([]string, len(events)),
+		genomicLocations:       make([]gnapi.GenomicLocation, len(events)),
+		uniqueGenomicLocations: make([]gnapi.GenomicLocation, 0),
+		recordIndices:          make(map[string][]int),
+	}
+	for i, a := range events {
+		explanation, err := gn.verifyReferenceAllele(a)
+		req.explanations[i] = explanation
+		if err != nil {
+			req.statuses[i] = AnnotationStatus{
+				Code:     StatusReferenceMismatch,
+				Reason:   err.Error(),
+				QueryKey: buildEventKey(a),
+			}
+			continue
+		}
+		loc, err := getGenomicLocation(a)
+		if err != nil {
+			req.statuses[i] = AnnotationStatus{
+				Code:     StatusInvalidInput,
+				Reason:   err.Error(),
+				QueryKey: buildEventKey(a),
+			}
+			continue
+		}
+		req.genomicLocations[i] = loc
+
+		key := buildGenomicLocationKey(loc)
+		if _, ok := req.recordIndices[key]; !ok {
+			req.uniqueGenomicLocations = append(req.uniqueGenomicLocations, loc)
+		}
+		req.recordIndices[key] = append(req.recordIndices[key], i)
+	}
+	return req
+}
+
+func getGenomicLocation(e *tt.Event) (gnapi.GenomicLocation, error) {
+	if err := validateEvent(e); err != nil {
+		return gnapi.GenomicLocation{}, err
+	}
+	start, _ := strconv.ParseInt(strings.TrimSpace(e.StartPosition), 10, 32)
+	end, _ := strconv.ParseInt(strings.TrimSpace(e.EndPosition), 10, 32)
 
 	gloc := gnapi.NewGenomicLocation(e.Chromosome,
 		int32(start),
 		int32(end),
 		e.ReferenceAllele,
 		resolveTumorSeqAlleleFromInput(e.ReferenceAllele, e.TumorSeqAllele1, e.TumorSeqAllele2))
-	return *gloc
+	return *gloc, nil
+}
+
+// enrichmentFields returns the Genome Nexus fields requested for every variant.
+func (gn GNAnnotatorService) enrichmentFields() []string {
+	fields := []string{"annotation_summary", "my_variant_info", "mutation_assessor"}
+	if len(gn.fields) > 0 {
+		fields = slices.Clone(gn.fields)
+	}
+	for _, e := range gn.enrichments {
+		if f := e.gnField(); f != "" && !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// useCache reports whether annotations requested with fields are read from and
+// stored in the cache. OncoKB annotations are never cached: whether Genome
+// Nexus returns them depends on the token, which is not part of the cache key,
+// and their content is licensed.
+func (gn GNAnnotatorService) useCache(fields []string) bool {
+	return gn.cache != nil && !slices.Contains(fields, EnrichmentOncokb.gnField())
+}
+
+// batchResult is the outcome of a single batched Genome Nexus request.
+type batchResult struct {
+	batch              []gnapi.GenomicLocation
+	variantAnnotations []gnapi.VariantAnnotation
+	timing             BatchTiming
+	err                error
+}
+
+// fetchBatches requests annotations for genomicLocations in batches of
+// batchSize, sending up to concurrency batches at a time. Results are returned
+// in batch order.
+func (gn GNAnnotatorService) fetchBatches(
+	isoformOverrideSource string,
+	genomicLocations []gnapi.GenomicLocation,
+) []batchResult {
+	results := make([]batchResult, 0)
+	for start := 0; start < len(genomicLocations); start += gn.batchSize {
+		results = append(results, batchResult{batch: genomicLocations[start:min(start+gn.batchSize, len(genomicLocations))]})
+	}
+
+	sem := make(chan struct{}, max(gn.concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(r *batchResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.variantAnnotations, r.timing, r.err = gn.getVariantAnnotationsWithRetry(isoformOverrideSource, r.batch)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
 }
 
 func (gn GNAnnotatorService) getVariantAnnotations(
 	isoformOverrideSource string,
 	genomicLocations []gnapi.GenomicLocation,
 ) ([]gnapi.VariantAnnotation, error) {
-	fields := make([]string, 0)
-	fields = append(fields, "annotation_summary", "my_variant_info", "mutation_assessor")
+	fields := gn.enrichmentFields()
 	x := gn.client.AnnotationControllerAPI.FetchVariantAnnotationByGenomicLocationPOST(gn.ctxAccessToken).
 		GenomicLocations(genomicLocations)
 	x = x.Fields(fields)
@@ -125,26 +397,62 @@ func (gn GNAnnotatorService) getVariantAnnotations(
 
 	variantAnnotations, r, err := x.Execute()
 	if err != nil {
-		return variantAnnotations, fmt.Errorf(
+		return variantAnnotations, gn.redactTokens(fmt.Errorf(
 			"Error calling Genome Nexus annotation service: %v\nFull HTTP response: %v\n",
 			err,
 			r,
-		)
+		))
 	}
 	return variantAnnotations, nil
 }
 
+// getVariantAnnotationsWithRetry calls getVariantAnnotations, retrying failed
+// requests up to maxRetries times with a linearly increasing backoff.
+func (gn GNAnnotatorService) getVariantAnnotationsWithRetry(
+	isoformOverrideSource string,
+	genomicLocations []gnapi.GenomicLocation,
+) ([]gnapi.VariantAnnotation, BatchTiming, error) {
+	timing := BatchTiming{Size: len(genomicLocations)}
+	began := time.Now()
+
+	var variantAnnotations []gnapi.VariantAnnotation
+	var err error
+	for attempt := 0; attempt <= gn.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-gn.ctxAccessToken.Done():
+				err = gn.ctxAccessToken.Err()
+			case <-time.After(gn.retryBackoff * time.Duration(attempt)):
+			}
+			if gn.ctxAccessToken.Err() != nil {
+				break
+			}
+		}
+		timing.Attempts++
+		variantAnnotations, err = gn.getVariantAnnotations(isoformOverrideSource, genomicLocations)
+		if err == nil {
+			break
+		}
+	}
+	timing.Duration = time.Since(began)
+	if err != nil {
+		timing.Error = err.Error()
+	}
+	return variantAnnotations, timing, err
+}
+
 func (gn GNAnnotatorService) mapResponseToEvent(
 	variantAnnotation gnapi.VariantAnnotation,
 	genomicLocation gnapi.GenomicLocation,
 	event *tt.Event,
-) {
-	if !*variantAnnotation.SuccessfullyAnnotated {
-		event.AnnotationStatus = fmt.Sprintf(
-			"FAILURE: Unsuccessful variant annotation for genomicLocation: %v",
-			variantAnnotation,
-		)
-		return
+) AnnotationStatus {
+	if variantAnnotation.SuccessfullyAnnotated == nil || !*variantAnnotation.SuccessfullyAnnotated {
+		return AnnotationStatus{
+			Code:         StatusGNUnsuccessful,
+			Reason:       "Unsuccessful variant annotation",
+			QueryKey:     variantAnnotation.OriginalVariantQuery,
+			ErrorMessage: variantAnnotation.GetErrorMessage(),
+		}
 	}
 	canonicalTranscript := getCanonicalTranscript(variantAnnotation)
 	// Taken from GN response (default)
@@ -192,7 +500,7 @@ func (gn GNAnnotatorService) mapResponseToEvent(
 	) // annotationUtil.resolveRefSeq(canonicalTranscript)
 	event.Codons = resolveCodonChange(canonicalTranscript)
 	event.Consequence = resolveConsequence(canonicalTranscript)
-	event.ProteinPosition = resolveProteinPosition(canonicalTranscript)
+	event.ProteinPosition = resolveProteinPosition(canonicalTranscript, event, gn.proteinPositionRange)
 	event.ExonNumber = resolveExon(canonicalTranscript)
 
 	// Taken from GN response (Polyphen)
@@ -216,20 +524,20 @@ func (gn GNAnnotatorService) mapResponseToEvent(
 	event.ReferenceAllele, event.TumorSeqAllele1, event.TumorSeqAllele2 = resolveRefAndTumorSeqAlleles(
 		variantAnnotation,
 		*event,
-		"true",
+		gn.stripMatchingBases,
 	)
 	// ======================================
 
-	// gnomAD allele frequencies (from MyVariantInfo.GnomadExome)
-	event.GnomadAf = resolveGnomadAF(variantAnnotation)
-	event.GnomadAfrAf = resolveGnomadAfrAF(variantAnnotation)
-	event.GnomadAmrAf = resolveGnomadAmrAF(variantAnnotation)
-	event.GnomadAsjAf = resolveGnomadAsjAF(variantAnnotation)
-	event.GnomadEasAf = resolveGnomadEasAF(variantAnnotation)
-	event.GnomadFinAf = resolveGnomadFinAF(variantAnnotation)
-	event.GnomadNfeAf = resolveGnomadNfeAF(variantAnnotation)
-	event.GnomadOthAf = resolveGnomadOthAF(variantAnnotation)
-	event.GnomadSasAf = resolveGnomadSasAF(variantAnnotation)
+	// gnomAD allele frequencies (from MyVariantInfo.GnomadExome by default, see WithGnomadPreference)
+	event.GnomadAf = resolveGnomadAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadAfrAf = resolveGnomadAfrAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadAmrAf = resolveGnomadAmrAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadAsjAf = resolveGnomadAsjAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadEasAf = resolveGnomadEasAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadFinAf = resolveGnomadFinAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadNfeAf = resolveGnomadNfeAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadOthAf = resolveGnomadOthAF(variantAnnotation, gn.gnomadPreference...)
+	event.GnomadSasAf = resolveGnomadSasAF(variantAnnotation, gn.gnomadPreference...)
 
 	// Mutation Assessor
 	event.MaFunctionalImpactScore = resolveMaFunctionalImpactScore(variantAnnotation)
@@ -274,7 +582,7 @@ func (gn GNAnnotatorService) mapResponseToEvent(
 	event.VepVariantClass = resolveVepVariantClass(rawTC)
 	event.VepAllEffects = resolveVepAllEffects(rawTC)
 
-	event.AnnotationStatus = "SUCCESS"
+	return AnnotationStatus{Code: StatusSuccess, QueryKey: variantAnnotation.OriginalVariantQuery}
 }
 
 // buildGenomicLocationKey constructs a stable key for a genomic location using
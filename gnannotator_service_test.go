@@ -0,0 +1,492 @@
+package genome_nexus_annotator_go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// newFakeGNServer starts a Genome Nexus stand-in that answers annotation
+// requests with annotate and reports its version from /version.
+func newFakeGNServer(t testing.TB, annotate func(gl gnapi.GenomicLocation) (map[string]interface{}, bool)) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/annotation/genomic", func(w http.ResponseWriter, r *http.Request) {
+		var locations []gnapi.GenomicLocation
+		if err := json.NewDecoder(r.Body).Decode(&locations); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := make([]map[string]interface{}, 0, len(locations))
+		for _, gl := range locations {
+			if va, ok := annotate(gl); ok {
+				resp = append(resp, va)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"genomeNexus":{"server":{"version":"test-1.0"}},"vep":{"server":{"version":"112"},"cache":{"version":"112_GRCh37"}}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fakeAnnotation is a minimal successful Genome Nexus response for gl.
+func fakeAnnotation(gl gnapi.GenomicLocation, hugoSymbol string) map[string]interface{} {
+	return map[string]interface{}{
+		"originalVariantQuery":   buildGenomicLocationKey(gl),
+		"variant":                buildGenomicLocationKey(gl),
+		"successfully_annotated": true,
+		"annotation_summary": map[string]interface{}{
+			"genomicLocation": gl,
+			"transcriptConsequences": []map[string]interface{}{
+				{"transcriptId": "ENST00000000001", "hugoGeneSymbol": hugoSymbol, "variantClassification": "Missense_Mutation"},
+			},
+		},
+	}
+}
+
+func newTestEvent(chromosome, start, end, ref, alt string) *tt.Event {
+	return &tt.Event{
+		Chromosome:      chromosome,
+		StartPosition:   start,
+		EndPosition:     end,
+		ReferenceAllele: ref,
+		TumorSeqAllele1: ref,
+		TumorSeqAllele2: alt,
+	}
+}
+
+func TestAnnotateTempoMessageEventsStatuses(t *testing.T) {
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		switch gl.Chromosome {
+		case "1":
+			return fakeAnnotation(gl, "MUTYH"), true
+		case "4":
+			t.Errorf("invalid event was sent to Genome Nexus: %s", buildGenomicLocationKey(gl))
+		case "2":
+			return map[string]interface{}{
+				"originalVariantQuery":   buildGenomicLocationKey(gl),
+				"successfully_annotated": false,
+				"errorMessage":           "variant could not be annotated",
+			}, true
+		}
+		return nil, false
+	})
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+
+	tm := &tt.TempoMessage{Events: []*tt.Event{
+		newTestEvent("1", "45797228", "45797228", "C", "T"),
+		newTestEvent("2", "100", "100", "A", "G"),
+		newTestEvent("3", "100", "100", "A", "G"),
+		newTestEvent("4", "abc", "100", "A", "G"),
+	}}
+	report, err := gn.AnnotateTempoMessageEvents(isoformOverrideString, tm)
+	if err != nil {
+		t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+	}
+	statuses := report.Statuses
+
+	expected := []struct {
+		code             AnnotationStatusCode
+		annotationStatus string
+	}{
+		{StatusSuccess, "SUCCESS"},
+		{StatusGNUnsuccessful, "FAILURE: GN_UNSUCCESSFUL: 2,100,100,A,G"},
+		{StatusNoResponse, "FAILURE: NO_RESPONSE: 3,100,100,A,G"},
+		{StatusInvalidInput, "FAILURE: INVALID_INPUT: 4,abc,100,A,G"},
+	}
+	for i, e := range expected {
+		if statuses[i].Code != e.code {
+			t.Errorf("event %d: expected status %q but got %q", i, e.code, statuses[i].Code)
+		}
+		if tm.Events[i].AnnotationStatus != e.annotationStatus {
+			t.Errorf("event %d: expected AnnotationStatus %q but got %q", i, e.annotationStatus, tm.Events[i].AnnotationStatus)
+		}
+	}
+	if statuses[1].ErrorMessage != "variant could not be annotated" {
+		t.Errorf("expected the Genome Nexus error message but got %q", statuses[1].ErrorMessage)
+	}
+	if tm.Events[0].HugoSymbol != "MUTYH" {
+		t.Errorf("expected HugoSymbol %q but got %q", "MUTYH", tm.Events[0].HugoSymbol)
+	}
+}
+
+func TestAnnotateTempoMessageEventsContext(t *testing.T) {
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "EGFR"), true
+	})
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+
+	tm := &tt.TempoMessage{Events: []*tt.Event{newTestEvent("7", "55249071", "55249071", "C", "T")}}
+	report, err := gn.AnnotateTempoMessageEventsContext(context.Background(), isoformOverrideString, tm)
+	if err != nil || report.Succeeded() != 1 || tm.Events[0].HugoSymbol != "EGFR" {
+		t.Fatalf("expected the event to be annotated but got %+v (%v)", report.StatusCounts, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tm = &tt.TempoMessage{Events: []*tt.Event{newTestEvent("7", "55249071", "55249071", "C", "T")}}
+	report, err = gn.AnnotateTempoMessageEventsContext(ctx, isoformOverrideString, tm)
+	if err == nil || report.Statuses[0].Code != StatusTransportError || tm.Events[0].HugoSymbol != "" {
+		t.Errorf("expected a cancelled context to stop the request but got %+v (%v)", report.StatusCounts, err)
+	}
+}
+
+func TestAnnotateTempoMessageEventsReport(t *testing.T) {
+	var requests atomic.Int32
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "KRAS"), gl.Chromosome != "3"
+	})
+	failFirst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/annotation/genomic" && requests.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer failFirst.Close()
+
+	gn, err := NewGNAnnotatorService(context.Background(), "", failFirst.URL, WithBatchSize(1), WithRetries(1, 0))
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	tm := &tt.TempoMessage{Events: []*tt.Event{
+		newTestEvent("12", "25398284", "25398284", "C", "T"),
+		newTestEvent("12", "25398284", "25398284", "C", "T"),
+		newTestEvent("3", "100", "100", "A", "G"),
+	}}
+	report, err := gn.AnnotateTempoMessageEvents(isoformOverrideString, tm)
+	if err != nil {
+		t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+	}
+	if report.Succeeded() != 2 || report.Failed() != 1 {
+		t.Errorf("expected 2 succeeded and 1 failed events but got %d and %d", report.Succeeded(), report.Failed())
+	}
+	if report.UniqueQueryKeys != 2 || report.SharedKeyEvents != 2 {
+		t.Errorf("expected 2 unique keys and 2 shared key events but got %d and %d", report.UniqueQueryKeys, report.SharedKeyEvents)
+	}
+	if len(report.Batches) != 2 || report.Retries != 1 {
+		t.Errorf("expected 2 batches and 1 retry but got %d and %d", len(report.Batches), report.Retries)
+	}
+	if len(report.FailedQueryKeys) != 1 || report.FailedQueryKeys[0] != "3,100,100,A,G" {
+		t.Errorf("expected failed query key %q but got %v", "3,100,100,A,G", report.FailedQueryKeys)
+	}
+	if report.GenomeNexusVersion != "test-1.0" || report.VepCacheVersion != "112_GRCh37" {
+		t.Errorf("expected Genome Nexus and VEP cache versions but got %q and %q", report.GenomeNexusVersion, report.VepCacheVersion)
+	}
+}
+
+func TestAnnotateTempoMessages(t *testing.T) {
+	var mu sync.Mutex
+	queried := make(map[string]int)
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		queried[buildGenomicLocationKey(gl)]++
+		return fakeAnnotation(gl, "BRAF"), gl.Chromosome != "3"
+	})
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	tms := []*tt.TempoMessage{
+		{CmoSampleId: "S1", Events: []*tt.Event{
+			newTestEvent("7", "140453136", "140453136", "A", "T"),
+			newTestEvent("3", "100", "100", "A", "G"),
+		}},
+		{CmoSampleId: "S2", Events: []*tt.Event{
+			newTestEvent("7", "140453136", "140453136", "A", "T"),
+			newTestEvent("1", "x", "100", "A", "G"),
+		}},
+	}
+	mr, err := gn.AnnotateTempoMessages(isoformOverrideString, tms)
+	if err != nil {
+		t.Fatalf("AnnotateTempoMessages failed: %v", err)
+	}
+	mu.Lock()
+	for key, n := range queried {
+		if n != 1 {
+			t.Errorf("genomic location %s was sent %d times", key, n)
+		}
+	}
+	mu.Unlock()
+	if mr.TotalEvents != 4 || mr.UniqueQueryKeys != 2 || mr.DeduplicatedEvents != 1 || mr.CrossMessageQueryKeys != 1 {
+		t.Errorf("unexpected dedup statistics: %+v", mr)
+	}
+	if mr.Reports[0].Succeeded() != 1 || mr.Reports[0].StatusCounts[StatusNoResponse] != 1 {
+		t.Errorf("unexpected report for first message: %+v", mr.Reports[0].StatusCounts)
+	}
+	if mr.Reports[1].Succeeded() != 1 || mr.Reports[1].StatusCounts[StatusInvalidInput] != 1 {
+		t.Errorf("unexpected report for second message: %+v", mr.Reports[1].StatusCounts)
+	}
+	if tms[1].Events[0].HugoSymbol != "BRAF" {
+		t.Errorf("expected the shared annotation on the second message but got %q", tms[1].Events[0].HugoSymbol)
+	}
+}
+
+func TestReannotateTempoMessageEvents(t *testing.T) {
+	var mu sync.Mutex
+	queried := make(map[string]int)
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		queried[buildGenomicLocationKey(gl)]++
+		return fakeAnnotation(gl, "PIK3CA"), true
+	})
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	annotated := newTestEvent("3", "178936091", "178936091", "G", "A")
+	annotated.AnnotationStatus = "SUCCESS"
+	annotated.HugoSymbol = "KEEP"
+	failed := newTestEvent("3", "178952085", "178952085", "A", "G")
+	failed.AnnotationStatus = "FAILURE: TRANSPORT_ERROR: 3,178952085,178952085,A,G"
+	tm := &tt.TempoMessage{Events: []*tt.Event{annotated, failed}}
+
+	current, err := gn.CurrentAnnotationVersion()
+	if err != nil || current != "genome-nexus=test-1.0;vep=112;vep-cache=112_GRCh37" {
+		t.Fatalf("unexpected current annotation version %q (%v)", current, err)
+	}
+	report, err := gn.ReannotateTempoMessageEvents(isoformOverrideString, tm, current)
+	if err != nil {
+		t.Fatalf("ReannotateTempoMessageEvents failed: %v", err)
+	}
+	if report.Statuses[0].Code != StatusSkippedCached || report.Statuses[1].Code != StatusSuccess {
+		t.Errorf("expected skipped and success statuses but got %q and %q", report.Statuses[0].Code, report.Statuses[1].Code)
+	}
+	mu.Lock()
+	if annotated.HugoSymbol != "KEEP" || queried["3,178936091,178936091,G,A"] != 0 {
+		t.Errorf("expected the successfully annotated event to be left untouched")
+	}
+	mu.Unlock()
+	if failed.HugoSymbol != "PIK3CA" || failed.AnnotationStatus != "SUCCESS" {
+		t.Errorf("expected the failed event to be annotated again")
+	}
+
+	// a different recorded version annotates every event again
+	report, err = gn.ReannotateTempoMessageEvents(isoformOverrideString, tm, "genome-nexus=old;vep=old;vep-cache=old")
+	if err != nil {
+		t.Fatalf("ReannotateTempoMessageEvents failed: %v", err)
+	}
+	if report.Succeeded() != 2 || annotated.HugoSymbol != "PIK3CA" {
+		t.Errorf("expected both events to be annotated again but got %+v", report.StatusCounts)
+	}
+}
+
+func TestAnnotationProvenance(t *testing.T) {
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "EGFR"), true
+	})
+	versionRequests := 0
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			versionRequests++
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+
+	gn, err := NewGNAnnotatorService(context.Background(), "", counting.URL)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	var report *AnnotationReport
+	for i := 0; i < 2; i++ {
+		tm := &tt.TempoMessage{Events: []*tt.Event{newTestEvent("7", "55249071", "55249071", "C", "T")}}
+		if report, err = gn.AnnotateTempoMessageEvents(isoformOverrideString, tm); err != nil {
+			t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+		}
+	}
+	if versionRequests != 1 {
+		t.Errorf("expected the Genome Nexus info to be fetched once but it was fetched %d times", versionRequests)
+	}
+	p := report.Provenance
+	if p.GenomeNexusVersion != "test-1.0" || p.VepVersion != "112" || p.IsoformOverrideSource != isoformOverrideString || p.AnnotatedAt.IsZero() {
+		t.Errorf("unexpected provenance: %+v", p)
+	}
+	if _, err := gn.RefreshGenomeNexusInfo(); err != nil || versionRequests != 2 {
+		t.Errorf("expected RefreshGenomeNexusInfo to fetch the info again (err: %v)", err)
+	}
+
+	gn.(GNAnnotatorService).infoCache.fetchedAt = time.Now().Add(-genomeNexusInfoTTL)
+	if _, err = gn.AnnotateTempoMessageEvents(isoformOverrideString, &tt.TempoMessage{}); err != nil || versionRequests != 3 {
+		t.Errorf("expected the expired info to be fetched again (err: %v)", err)
+	}
+}
+
+func TestReannotateAfterUpgrade(t *testing.T) {
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "PIK3CA"), true
+	})
+	version := "test-1.0"
+	upgradable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/version" {
+			fmt.Fprintf(w, `{"genomeNexus":{"server":{"version":%q}}}`, version)
+			return
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer upgradable.Close()
+
+	gn, err := NewGNAnnotatorService(context.Background(), "", upgradable.URL, WithCache(NewMemoryCache()))
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	tm := &tt.TempoMessage{Events: []*tt.Event{newTestEvent("3", "178936091", "178936091", "G", "A")}}
+	first, err := gn.AnnotateTempoMessageEvents(isoformOverrideString, tm)
+	if err != nil {
+		t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+	}
+
+	// the cached source info still holds the old version
+	version = "test-2.0"
+	report, err := gn.ReannotateTempoMessageEvents(isoformOverrideString, tm, first.AnnotationVersion())
+	if err != nil {
+		t.Fatalf("ReannotateTempoMessageEvents failed: %v", err)
+	}
+	if report.Statuses[0].Code != StatusSuccess || report.CacheHits != 0 || report.Provenance.GenomeNexusVersion != "test-2.0" {
+		t.Errorf("expected the event to be annotated again by the upgraded Genome Nexus but got %+v", report)
+	}
+}
+
+func TestAnnotateTempoMessageEventsDryRun(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	gn, err := NewGNAnnotatorService(context.Background(), "secret", server.URL, WithBatchSize(1))
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	tm := &tt.TempoMessage{Events: []*tt.Event{
+		newTestEvent("7", "55249071", "55249071", "C", "T"),
+		newTestEvent("17", "7577120", "7577120", "C", "C"),
+		newTestEvent("7", "55249071", "55249071", "C", "T"),
+		newTestEvent("", "1", "1", "A", "T"),
+	}}
+	tm.Events[1].TumorSeqAllele1 = "-"
+	before, _ := json.Marshal(tm)
+
+	report, err := gn.DryRunTempoMessageEvents(isoformOverrideString, tm)
+	if err != nil {
+		t.Fatalf("DryRunTempoMessageEvents failed: %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("expected no requests to Genome Nexus but got %d", n)
+	}
+	if after, _ := json.Marshal(tm); string(after) != string(before) {
+		t.Errorf("expected the events to be left untouched")
+	}
+
+	if report.StatusCounts[StatusDryRun] != 3 || report.StatusCounts[StatusInvalidInput] != 1 || report.Failed() != 1 {
+		t.Errorf("unexpected status counts: %v", report.StatusCounts)
+	}
+	preview := report.DryRun
+	if preview == nil || len(preview.Requests) != 2 {
+		t.Fatalf("expected a preview of 2 batched requests but got %+v", preview)
+	}
+	if indices := preview.EventIndexes["7,55249071,55249071,C,T"]; len(indices) != 2 || indices[0] != 0 || indices[1] != 2 {
+		t.Errorf("unexpected event indices: %v", preview.EventIndexes)
+	}
+	req := preview.Requests[0]
+	if req.Method != http.MethodPost || !strings.HasPrefix(req.URL, server.URL+"/annotation/genomic?") {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL)
+	}
+	if strings.Contains(req.URL, "secret") || !strings.Contains(req.URL, "isoformOverrideSource="+isoformOverrideString) {
+		t.Errorf("expected the token to be redacted and the isoform to be set in %q", req.URL)
+	}
+	var body []gnapi.GenomicLocation
+	if err := json.Unmarshal(req.Body, &body); err != nil || len(body) != 1 || buildGenomicLocationKey(body[0]) != req.QueryKeys[0] {
+		t.Errorf("unexpected request body %s (%v)", req.Body, err)
+	}
+	if preview.Requests[1].QueryKeys[0] != "17,7577120,7577120,C,-" {
+		t.Errorf("expected the tumor allele to be resolved but got %v", preview.Requests[1].QueryKeys)
+	}
+
+	// the dry run does not carry over to the following calls
+	gn.AnnotateTempoMessageEvents(isoformOverrideString, tm)
+	if requests.Load() == 0 {
+		t.Errorf("expected AnnotateTempoMessageEvents to send its requests")
+	}
+}
+
+func TestAnnotateTempoMessageEventsCache(t *testing.T) {
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "KRAS"), true
+	})
+	var annotationRequests atomic.Int32
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/annotation/genomic" {
+			annotationRequests.Add(1)
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+
+	cache, err := NewFileCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileCache failed: %v", err)
+	}
+	newMessage := func() *tt.TempoMessage {
+		return &tt.TempoMessage{Events: []*tt.Event{
+			newTestEvent("12", "25398284", "25398284", "C", "T"),
+			newTestEvent("12", "25398285", "25398285", "C", "A"),
+		}}
+	}
+	for run, expectedHits := range []int{0, 2} {
+		// a new service per run, sharing only the cache directory
+		gn, err := NewGNAnnotatorService(context.Background(), "", counting.URL, WithCache(cache), WithBatchSize(1), WithConcurrency(2))
+		if err != nil {
+			t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+		}
+		tm := newMessage()
+		report, err := gn.AnnotateTempoMessageEvents(isoformOverrideString, tm)
+		if err != nil {
+			t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+		}
+		if report.CacheHits != expectedHits || report.Succeeded() != 2 || tm.Events[1].HugoSymbol != "KRAS" {
+			t.Errorf("run %d: expected %d cache hits and 2 annotated events but got %+v", run, expectedHits, report)
+		}
+	}
+	if n := annotationRequests.Load(); n != 2 {
+		t.Errorf("expected only the first run to request annotations but got %d requests", n)
+	}
+
+	// OncoKB annotations depend on the token and are neither read nor stored
+	lru := NewLRUCache(10)
+	for run := 0; run < 2; run++ {
+		gn, err := NewGNAnnotatorService(context.Background(), "", counting.URL, WithCache(lru), WithOncokbToken("oncokb-secret"))
+		if err != nil {
+			t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+		}
+		report, err := gn.AnnotateTempoMessageEvents(isoformOverrideString, newMessage())
+		if err != nil {
+			t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+		}
+		if report.CacheHits != 0 || lru.Len() != 0 {
+			t.Errorf("run %d: expected OncoKB annotations not to be cached but got %d hits and %d entries", run, report.CacheHits, lru.Len())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package genome_nexus_annotator_go
+
+import (
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+// GnomadSource is a source of gnomAD allele frequencies.
+type GnomadSource string
+
+const (
+	GnomadSourceExome  GnomadSource = "exome"
+	GnomadSourceGenome GnomadSource = "genome"
+	// GnomadSourceMax takes every frequency from the exome or genome data
+	// set, whichever is higher.
+	GnomadSourceMax GnomadSource = "max"
+)
+
+// defaultGnomadPreference only reads the exome data set, as the Java pipeline.
+var defaultGnomadPreference = []GnomadSource{GnomadSourceExome}
+
+// gnomadPopulations lists the gnomAD populations, keyed as in
+// GnomadEnrichment.PopulationAFs.
+var gnomadPopulations = []struct {
+	name   string
+	af     func(af *gnapi.AlleleFrequency) float64
+	popmax bool
+}{
+	{"afr", func(af *gnapi.AlleleFrequency) float64 { return af.AfAfr }, true},
+	{"amr", func(af *gnapi.AlleleFrequency) float64 { return af.AfAmr }, true},
+	{"asj", func(af *gnapi.AlleleFrequency) float64 { return af.AfAsj }, false},
+	{"eas", func(af *gnapi.AlleleFrequency) float64 { return af.AfEas }, true},
+	{"fin", func(af *gnapi.AlleleFrequency) float64 { return af.AfFin }, false},
+	{"nfe", func(af *gnapi.AlleleFrequency) float64 { return af.AfNfe }, true},
+	{"oth", func(af *gnapi.AlleleFrequency) float64 { return af.AfOth }, false},
+	{"sas", func(af *gnapi.AlleleFrequency) float64 { return af.AfSas }, true},
+}
+
+// GnomadEnrichment holds the gnomAD allele frequencies of a variant and the
+// data set each one was taken from.
+type GnomadEnrichment struct {
+	AF float64 `json:"af"`
+	// PopulationAFs holds the allele frequency of each population, keyed by
+	// afr, amr, asj, eas, fin, nfe, oth and sas.
+	PopulationAFs map[string]float64 `json:"populationAfs"`
+	// Sources holds the data set of AF, keyed by "af", and of every population
+	// allele frequency.
+	Sources map[string]GnomadSource `json:"sources"`
+	// Popmax is the highest allele frequency of the afr, amr, eas, nfe and sas
+	// populations; like gnomAD it leaves out the bottlenecked asj and fin
+	// populations and oth.
+	Popmax           float64      `json:"popmax"`
+	PopmaxPopulation string       `json:"popmaxPopulation,omitempty"`
+	PopmaxSource     GnomadSource `json:"popmaxSource,omitempty"`
+}
+
+// getGnomadAF returns the AlleleFrequency of a gnomAD data set or nil if
+// unavailable.
+func getGnomadAF(va gnapi.VariantAnnotation, source GnomadSource) *gnapi.AlleleFrequency {
+	if va.MyVariantInfo == nil {
+		return nil
+	}
+	mviAnnot, ok := va.MyVariantInfo.GetAnnotationOk()
+	if !ok || mviAnnot == nil {
+		return nil
+	}
+	var gnomad *gnapi.Gnomad
+	switch source {
+	case GnomadSourceExome:
+		gnomad, ok = mviAnnot.GetGnomadExomeOk()
+	case GnomadSourceGenome:
+		gnomad, ok = mviAnnot.GetGnomadGenomeOk()
+	default:
+		return nil
+	}
+	if !ok || gnomad == nil {
+		return nil
+	}
+	af, ok := gnomad.GetAlleleFrequencyOk()
+	if !ok || af == nil {
+		return nil
+	}
+	return af
+}
+
+// resolveGnomadValue returns a gnomAD allele frequency from the first source
+// of preference with data, and that source. An empty preference reads the
+// exome data set.
+func resolveGnomadValue(va gnapi.VariantAnnotation, value func(*gnapi.AlleleFrequency) float64, preference []GnomadSource) (float64, GnomadSource, bool) {
+	if len(preference) == 0 {
+		preference = defaultGnomadPreference
+	}
+	for _, source := range preference {
+		if source == GnomadSourceMax {
+			exome, genome := getGnomadAF(va, GnomadSourceExome), getGnomadAF(va, GnomadSourceGenome)
+			switch {
+			case exome == nil && genome == nil:
+				continue
+			case genome == nil || (exome != nil && value(exome) >= value(genome)):
+				return value(exome), GnomadSourceExome, true
+			default:
+				return value(genome), GnomadSourceGenome, true
+			}
+		}
+		if af := getGnomadAF(va, source); af != nil {
+			return value(af), source, true
+		}
+	}
+	return 0, "", false
+}
+
+// resolveGnomad returns the gnomAD allele frequencies of the variant, or nil
+// when none of the sources of preference has data.
+func resolveGnomad(va gnapi.VariantAnnotation, preference []GnomadSource) *GnomadEnrichment {
+	af, source, ok := resolveGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.Af }, preference)
+	if !ok {
+		return nil
+	}
+	enrichment := &GnomadEnrichment{
+		AF:            af,
+		PopulationAFs: make(map[string]float64, len(gnomadPopulations)),
+		Sources:       map[string]GnomadSource{"af": source},
+	}
+	for _, p := range gnomadPopulations {
+		af, source, _ := resolveGnomadValue(va, p.af, preference)
+		enrichment.PopulationAFs[p.name] = af
+		enrichment.Sources[p.name] = source
+		if p.popmax && (enrichment.PopmaxPopulation == "" || af > enrichment.Popmax) {
+			enrichment.Popmax = af
+			enrichment.PopmaxPopulation = p.name
+			enrichment.PopmaxSource = source
+		}
+	}
+	return enrichment
+}
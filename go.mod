@@ -5,6 +5,13 @@ go 1.24.2
 require (
 	github.com/genome-nexus/genome-nexus-go-api-client v1.0.6
 	github.mskcc.org/cdsi/cdsi-protobuf/tempo v0.0.0-20260423134801-614ced4d6bf8
+	google.golang.org/grpc v1.71.0
+	google.golang.org/protobuf v1.36.5
 )
 
-require google.golang.org/protobuf v1.36.5 // indirect
+require (
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
+)
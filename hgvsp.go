@@ -0,0 +1,342 @@
+package genome_nexus_annotator_go
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Utility functions for parsing, validating and converting HGVS protein notation
+between the three-letter (HGVSp, e.g. p.Gly382Asp) and one-letter
+(HGVSp_Short, e.g. p.G382D) forms.
+*/
+
+var aminoAcidThreeToOne = map[string]string{
+	"Ala": "A", "Arg": "R", "Asn": "N", "Asp": "D", "Cys": "C",
+	"Gln": "Q", "Glu": "E", "Gly": "G", "His": "H", "Ile": "I",
+	"Leu": "L", "Lys": "K", "Met": "M", "Phe": "F", "Pro": "P",
+	"Ser": "S", "Thr": "T", "Trp": "W", "Tyr": "Y", "Val": "V",
+	"Sec": "U", "Pyl": "O", "Ter": "*",
+}
+
+// unknownAminoAcid is the code of an unknown residue, Xaa in HGVSp and X in
+// HGVSp_Short. It is kept apart from the one-letter X read from HGVSp_Short,
+// which older VEP releases wrote for stop codons.
+const unknownAminoAcid = "Xaa"
+
+var aminoAcidOneToThree = func() map[string]string {
+	m := make(map[string]string, len(aminoAcidThreeToOne)+1)
+	for three, one := range aminoAcidThreeToOne {
+		m[one] = three
+	}
+	m[unknownAminoAcid] = "Xaa"
+	return m
+}()
+
+// hgvspChange is a parsed protein change. Amino acids are stored as one-letter
+// codes, with stop codons normalized to "*" and unknown residues stored as
+// unknownAminoAcid.
+type hgvspChange struct {
+	parens bool
+	// whole protein descriptions: "=", "?" or "0"
+	special  string
+	startAA  string
+	startPos string
+	endAA    string
+	endPos   string
+	// "", "=", "?", "del", "dup", "ins", "delins", "fs", "ext" or "splice"
+	kind string
+	alt  []string
+	// fs and ext tails, e.g. the "*5" in p.K10Rfs*5 or the "-5" in p.M1ext-5
+	tailStop bool
+	tailPos  string
+}
+
+// hgvspReader consumes a protein change body one token at a time and keeps
+// track of which amino acid notation was used.
+type hgvspReader struct {
+	s        string
+	i        int
+	sawThree bool
+	sawOne   bool
+}
+
+func (r *hgvspReader) done() bool {
+	return r.i >= len(r.s)
+}
+
+func (r *hgvspReader) consume(token string) bool {
+	if strings.HasPrefix(r.s[r.i:], token) {
+		r.i += len(token)
+		return true
+	}
+	return false
+}
+
+// aminoAcid reads one residue in either notation and returns its one-letter code.
+func (r *hgvspReader) aminoAcid() (string, bool) {
+	rest := r.s[r.i:]
+	if len(rest) >= 3 {
+		if rest[:3] == "Xaa" {
+			r.i += 3
+			r.sawThree = true
+			return unknownAminoAcid, true
+		}
+		if one, ok := aminoAcidThreeToOne[rest[:3]]; ok {
+			r.i += 3
+			r.sawThree = true
+			return one, true
+		}
+	}
+	if len(rest) >= 1 {
+		c := rest[:1]
+		if c == "*" {
+			r.i++
+			return c, true
+		}
+		// X is resolved once the kind of change is known, see parseHgvsp
+		if _, ok := aminoAcidOneToThree[c]; ok || c == "X" {
+			r.i++
+			r.sawOne = true
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// stop reads a stop codon in any of the Ter, * or X notations.
+func (r *hgvspReader) stop() bool {
+	if r.consume("Ter") {
+		r.sawThree = true
+		return true
+	}
+	return r.consume("*") || r.consume("X")
+}
+
+func (r *hgvspReader) digits() string {
+	start := r.i
+	for r.i < len(r.s) && r.s[r.i] >= '0' && r.s[r.i] <= '9' {
+		r.i++
+	}
+	return r.s[start:r.i]
+}
+
+func (r *hgvspReader) sequence() []string {
+	seq := make([]string, 0)
+	for {
+		aa, ok := r.aminoAcid()
+		if !ok {
+			return seq
+		}
+		seq = append(seq, aa)
+	}
+}
+
+// parseHgvsp parses HGVSp or HGVSp_Short, with or without a protein id prefix.
+func parseHgvsp(hgvsp string) (hgvspChange, error) {
+	var c hgvspChange
+	idx := strings.Index(hgvsp, "p.")
+	if idx < 0 {
+		return c, fmt.Errorf("hgvsp: %q is missing the p. prefix", hgvsp)
+	}
+	body := hgvsp[idx+2:]
+	if strings.HasPrefix(body, "(") && strings.HasSuffix(body, ")") {
+		c.parens = true
+		body = body[1 : len(body)-1]
+	}
+	if body == "=" || body == "?" || body == "0" {
+		c.special = body
+		return c, nil
+	}
+
+	r := &hgvspReader{s: body}
+	aa, ok := r.aminoAcid()
+	if !ok {
+		return c, fmt.Errorf("hgvsp: %q does not start with an amino acid", hgvsp)
+	}
+	c.startAA = aa
+	if c.startPos = r.digits(); c.startPos == "" {
+		return c, fmt.Errorf("hgvsp: %q is missing a protein position", hgvsp)
+	}
+	if r.consume("_") {
+		if r.consume("splice") {
+			c.kind = "splice"
+		} else {
+			if c.endAA, ok = r.aminoAcid(); !ok {
+				return c, fmt.Errorf("hgvsp: %q has an invalid range end", hgvsp)
+			}
+			if c.endPos = r.digits(); c.endPos == "" {
+				return c, fmt.Errorf("hgvsp: %q is missing the range end position", hgvsp)
+			}
+		}
+	}
+
+	if c.kind == "" {
+		switch {
+		case r.consume("delins"):
+			c.kind = "delins"
+			c.alt = r.sequence()
+		case r.consume("del"):
+			c.kind = "del"
+		case r.consume("dup"):
+			c.kind = "dup"
+		case r.consume("ins"):
+			c.kind = "ins"
+			c.alt = r.sequence()
+		case r.consume("="):
+			c.kind = "="
+		case r.consume("?"):
+			// unknown consequence, e.g. p.Met1? for a start loss
+			c.kind = "?"
+		default:
+			if aa, ok := r.aminoAcid(); ok {
+				c.alt = []string{aa}
+			}
+			if r.consume("fs") {
+				c.kind = "fs"
+				c.tailStop = r.stop()
+				if c.tailPos = r.digits(); c.tailPos == "" && r.consume("?") {
+					c.tailPos = "?"
+				}
+			} else if r.consume("ext") {
+				c.kind = "ext"
+				c.tailStop = r.stop()
+				if r.consume("-") {
+					c.tailPos = "-"
+				}
+				if c.tailPos += r.digits(); c.tailPos == "" && r.consume("?") {
+					c.tailPos = "?"
+				}
+			}
+		}
+	}
+
+	if !r.done() {
+		return c, fmt.Errorf("hgvsp: %q has unexpected trailing characters %q", hgvsp, r.s[r.i:])
+	}
+	if r.sawThree && r.sawOne {
+		return c, fmt.Errorf("hgvsp: %q mixes three-letter and one-letter amino acid codes", hgvsp)
+	}
+	switch c.kind {
+	case "":
+		if len(c.alt) == 0 {
+			return c, fmt.Errorf("hgvsp: %q is missing the variant amino acid", hgvsp)
+		}
+		if c.endPos != "" {
+			return c, fmt.Errorf("hgvsp: %q is a substitution over a range", hgvsp)
+		}
+	case "?":
+		if c.endPos != "" {
+			return c, fmt.Errorf("hgvsp: %q is an unknown consequence over a range", hgvsp)
+		}
+	case "ins":
+		if c.endPos == "" || len(c.alt) == 0 {
+			return c, fmt.Errorf("hgvsp: %q insertion needs a flanking range and inserted residues", hgvsp)
+		}
+	case "delins":
+		if len(c.alt) == 0 {
+			return c, fmt.Errorf("hgvsp: %q is missing the inserted residues", hgvsp)
+		}
+	}
+
+	// a one-letter X is the older notation for a stop codon everywhere but in
+	// splice sites, where VEP writes it for an unknown residue
+	if c.startAA == "X" {
+		c.startAA = "*"
+		if c.kind == "splice" {
+			c.startAA = unknownAminoAcid
+		}
+	}
+	if c.endAA == "X" {
+		c.endAA = "*"
+	}
+	for i := range c.alt {
+		if c.alt[i] == "X" {
+			c.alt[i] = "*"
+		}
+	}
+	return c, nil
+}
+
+func (c hgvspChange) format(aminoAcid func(string) string, stop string) string {
+	var b strings.Builder
+	b.WriteString("p.")
+	if c.parens {
+		b.WriteString("(")
+	}
+	if c.special != "" {
+		b.WriteString(c.special)
+	} else {
+		b.WriteString(aminoAcid(c.startAA) + c.startPos)
+		if c.endPos != "" {
+			b.WriteString("_" + aminoAcid(c.endAA) + c.endPos)
+		}
+		switch c.kind {
+		case "splice":
+			b.WriteString("_splice")
+		case "del", "dup", "=", "?", "ins", "delins":
+			b.WriteString(c.kind)
+		}
+		for _, aa := range c.alt {
+			b.WriteString(aminoAcid(aa))
+		}
+		if c.kind == "fs" || c.kind == "ext" {
+			b.WriteString(c.kind)
+			if c.tailStop {
+				b.WriteString(stop)
+			}
+			b.WriteString(c.tailPos)
+		}
+	}
+	if c.parens {
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+func (c hgvspChange) short() string {
+	return c.format(func(aa string) string {
+		if aa == unknownAminoAcid {
+			return "X"
+		}
+		return aa
+	}, "*")
+}
+
+func (c hgvspChange) long() (string, error) {
+	if c.kind == "splice" {
+		return "", fmt.Errorf("hgvsp: splice site changes have no three-letter notation")
+	}
+	return c.format(func(aa string) string { return aminoAcidOneToThree[aa] }, "Ter"), nil
+}
+
+// ValidateHgvsp returns an error describing why hgvsp is not valid HGVS
+// protein notation, in either the three-letter or one-letter form.
+func ValidateHgvsp(hgvsp string) error {
+	_, err := parseHgvsp(hgvsp)
+	return err
+}
+
+// hgvspToShort converts HGVSp (e.g. p.Lys10ArgfsTer5) to HGVSp_Short (e.g. p.K10Rfs*5).
+func hgvspToShort(hgvsp string) (string, error) {
+	c, err := parseHgvsp(hgvsp)
+	if err != nil {
+		return "", err
+	}
+	return c.short(), nil
+}
+
+// hgvspShortToLong converts HGVSp_Short (e.g. p.*100Qext*10) to HGVSp (e.g. p.Ter100GlnextTer10).
+func hgvspShortToLong(hgvspShort string) (string, error) {
+	c, err := parseHgvsp(hgvspShort)
+	if err != nil {
+		return "", err
+	}
+	return c.long()
+}
+
+// NormalizeHgvspShort rewrites HGVSp_Short so stop codons always use "*" (e.g.
+// p.R213X -> p.R213*), dropping any protein id prefix.
+func NormalizeHgvspShort(hgvspShort string) (string, error) {
+	return hgvspToShort(hgvspShort)
+}
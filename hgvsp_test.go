@@ -0,0 +1,109 @@
+package genome_nexus_annotator_go
+
+import (
+	"testing"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+func TestHgvspConversion(t *testing.T) {
+	tests := []struct {
+		hgvsp      string
+		hgvspShort string
+	}{
+		{"p.Gly382Asp", "p.G382D"},
+		{"p.Ala4_Pro11dup", "p.A4_P11dup"},
+		{"p.Lys10ArgfsTer5", "p.K10Rfs*5"},
+		{"p.Lys10fs", "p.K10fs"},
+		{"p.Ter100GlnextTer10", "p.*100Qext*10"},
+		{"p.Met1ext-5", "p.M1ext-5"},
+		{"p.Gly12_Leu13delinsValVal", "p.G12_L13delinsVV"},
+		{"p.Lys10_Leu11insGln", "p.K10_L11insQ"},
+		{"p.Glu746_Ala750del", "p.E746_A750del"},
+		{"p.Arg213Ter", "p.R213*"},
+		{"p.Val600=", "p.V600="},
+		{"p.(Gly12Val)", "p.(G12V)"},
+		{"p.=", "p.="},
+		{"p.Met1?", "p.M1?"},
+		{"p.(Met1?)", "p.(M1?)"},
+	}
+	for _, test := range tests {
+		short, err := hgvspToShort(test.hgvsp)
+		if err != nil || short != test.hgvspShort {
+			t.Errorf("hgvsp: %q; expected %q but got %q (err: %v)", test.hgvsp, test.hgvspShort, short, err)
+		}
+		long, err := hgvspShortToLong(test.hgvspShort)
+		if err != nil || long != test.hgvsp {
+			t.Errorf("hgvspShort: %q; expected %q but got %q (err: %v)", test.hgvspShort, test.hgvsp, long, err)
+		}
+	}
+}
+
+func TestNormalizeHgvspShort(t *testing.T) {
+	tests := map[string]string{
+		"p.R213X":                       "p.R213*",
+		"p.K10RfsX5":                    "p.K10Rfs*5",
+		"p.X100QextX10":                 "p.*100Qext*10",
+		"p.X125_splice":                 "p.X125_splice",
+		"ENSP00000372115.3:p.Gly382Asp": "p.G382D",
+	}
+	for input, expected := range tests {
+		got, err := NormalizeHgvspShort(input)
+		if err != nil || got != expected {
+			t.Errorf("hgvspShort: %q; expected %q but got %q (err: %v)", input, expected, got, err)
+		}
+	}
+	if _, err := hgvspShortToLong("p.X125_splice"); err == nil {
+		t.Errorf("expected an error converting a splice site to three-letter notation")
+	}
+}
+
+func TestHgvspUnknownAminoAcid(t *testing.T) {
+	c, err := parseHgvsp("p.Xaa12Val")
+	if err != nil || c.startAA != unknownAminoAcid {
+		t.Fatalf("expected Xaa to be read as an unknown residue but got %+v (err: %v)", c, err)
+	}
+	if long, err := c.long(); err != nil || long != "p.Xaa12Val" {
+		t.Errorf("expected p.Xaa12Val but got %q (err: %v)", long, err)
+	}
+	if short := c.short(); short != "p.X12V" {
+		t.Errorf("expected p.X12V but got %q", short)
+	}
+	if long, err := hgvspShortToLong("p.X12V"); err != nil || long != "p.Ter12Val" {
+		t.Errorf("expected a one-letter X to be read as a stop codon but got %q (err: %v)", long, err)
+	}
+}
+
+func TestResolveHgvspShortNormalizesStop(t *testing.T) {
+	hgvspShort := "p.R213X"
+	ct := gnapi.TranscriptConsequenceSummary{HgvspShort: &hgvspShort}
+	if got := resolveHgvspShort(ct); got != "p.R213*" {
+		t.Errorf("expected p.R213* but got %q", got)
+	}
+}
+
+func TestValidateHgvsp(t *testing.T) {
+	invalid := []string{
+		"",
+		"G382D",
+		"p.G",
+		"p.G382",
+		"p.Gly382D",
+		"p.G382Dfoo",
+		"p.G12_L13V",
+		"p.K10insQ",
+		"p.G12_L13delins",
+		"p.M1_K2?",
+		"p.M1?V",
+	}
+	for _, hgvsp := range []string{"p.Met1?", "p.M1?", "p.(Met1?)", "p.?"} {
+		if err := ValidateHgvsp(hgvsp); err != nil {
+			t.Errorf("hgvsp: %q; expected no validation error but got %v", hgvsp, err)
+		}
+	}
+	for _, hgvsp := range invalid {
+		if err := ValidateHgvsp(hgvsp); err == nil {
+			t.Errorf("hgvsp: %q; expected a validation error", hgvsp)
+		}
+	}
+}
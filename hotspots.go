@@ -0,0 +1,79 @@
+package genome_nexus_annotator_go
+
+import (
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+// Hotspot types reported by Genome Nexus.
+const (
+	HotspotTypeSingleResidue = "single residue"
+	HotspotTypeInFrameIndel  = "in-frame indel"
+	HotspotType3D            = "3d"
+	HotspotTypeSplice        = "splice"
+)
+
+// HotspotEnrichment lists the recurrent mutation hotspots a variant falls in.
+type HotspotEnrichment struct {
+	// IsHotspot is true when the variant falls in at least one hotspot.
+	IsHotspot bool      `json:"isHotspot"`
+	Hotspots  []Hotspot `json:"hotspots,omitempty"`
+}
+
+// Hotspot is a recurrent mutation hotspot of the canonical transcript.
+type Hotspot struct {
+	Type         string `json:"type"`
+	Residue      string `json:"residue,omitempty"`
+	TumorCount   int    `json:"tumorCount"`
+	TranscriptId string `json:"transcriptId,omitempty"`
+}
+
+// HasType reports whether the variant falls in a hotspot of hotspotType.
+func (h *HotspotEnrichment) HasType(hotspotType string) bool {
+	if h == nil {
+		return false
+	}
+	for _, hotspot := range h.Hotspots {
+		if hotspot.Type == hotspotType {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveHotspots returns the hotspots of the canonical transcript. Hotspots
+// without a transcript id are kept since they cannot be told apart.
+func resolveHotspots(gnResponse gnapi.VariantAnnotation, canonicalTranscript gnapi.TranscriptConsequenceSummary) *HotspotEnrichment {
+	enrichment := &HotspotEnrichment{}
+	if gnResponse.Hotspots == nil {
+		return enrichment
+	}
+	transcriptId := resolveTranscriptId(canonicalTranscript)
+	seen := make(map[Hotspot]bool)
+	for _, hotspots := range gnResponse.Hotspots.Annotation {
+		for _, h := range hotspots {
+			hotspot := Hotspot{}
+			if h.TranscriptId != nil {
+				hotspot.TranscriptId = *h.TranscriptId
+			}
+			if transcriptId != "" && hotspot.TranscriptId != "" && hotspot.TranscriptId != transcriptId {
+				continue
+			}
+			if h.Type != nil {
+				hotspot.Type = *h.Type
+			}
+			if h.Residue != nil {
+				hotspot.Residue = *h.Residue
+			}
+			if h.TumorCount != nil {
+				hotspot.TumorCount = int(*h.TumorCount)
+			}
+			if seen[hotspot] {
+				continue
+			}
+			seen[hotspot] = true
+			enrichment.Hotspots = append(enrichment.Hotspots, hotspot)
+		}
+	}
+	enrichment.IsHotspot = len(enrichment.Hotspots) > 0
+	return enrichment
+}
@@ -0,0 +1,143 @@
+// Package gntest provides the Genome Nexus server and GNAnnotator stand-ins
+// shared by the command and service tests.
+package gntest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	gn "github.com/genome-nexus/genome-nexus-go"
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// NewGNServer starts a Genome Nexus stand-in that answers annotation requests
+// with annotate and reports its version from /version.
+func NewGNServer(t testing.TB, annotate func(gl gnapi.GenomicLocation) (map[string]interface{}, bool)) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/annotation/genomic", func(w http.ResponseWriter, r *http.Request) {
+		var locations []gnapi.GenomicLocation
+		if err := json.NewDecoder(r.Body).Decode(&locations); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := make([]map[string]interface{}, 0, len(locations))
+		for _, gl := range locations {
+			if va, ok := annotate(gl); ok {
+				resp = append(resp, va)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"genomeNexus":{"server":{"version":"test-1.0"}},"vep":{"server":{"version":"112"},"cache":{"version":"112_GRCh37"}}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Annotation is a minimal successful Genome Nexus response for gl.
+func Annotation(gl gnapi.GenomicLocation, hugoSymbol string) map[string]interface{} {
+	query := fmt.Sprintf("%s,%d,%d,%s,%s", gl.Chromosome, gl.Start, gl.End, gl.ReferenceAllele, gl.VariantAllele)
+	return map[string]interface{}{
+		"originalVariantQuery":   query,
+		"variant":                query,
+		"successfully_annotated": true,
+		"annotation_summary": map[string]interface{}{
+			"genomicLocation": gl,
+			"transcriptConsequences": []map[string]interface{}{
+				{"transcriptId": "ENST00000000001", "hugoGeneSymbol": hugoSymbol, "variantClassification": "Missense_Mutation"},
+			},
+		},
+	}
+}
+
+// NewTestMessage returns a message of sample holding a single EGFR event.
+func NewTestMessage(sample string) *tt.TempoMessage {
+	return &tt.TempoMessage{CmoSampleId: sample, Events: []*tt.Event{{
+		Chromosome: "7", StartPosition: "55249071", EndPosition: "55249071",
+		ReferenceAllele: "C", TumorSeqAllele1: "C", TumorSeqAllele2: "T",
+	}}}
+}
+
+// Annotator is a GNAnnotator stand-in annotating every event with HugoSymbol,
+// or with the sample id of its message when HugoSymbol is empty.
+type Annotator struct {
+	gn.GNAnnotator
+	HugoSymbol string
+	// Err fails every request, leaving the events with TRANSPORT_ERROR.
+	Err error
+	// Delay is the time taken to annotate each message.
+	Delay time.Duration
+
+	mu       sync.Mutex
+	isoforms []string
+}
+
+// Isoforms returns the isoform override source of every annotation request.
+func (a *Annotator) Isoforms() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.isoforms...)
+}
+
+func (a *Annotator) GetGenomeNexusInfo() (*gnapi.AggregateSourceInfo, error) {
+	if a.Err != nil {
+		return nil, a.Err
+	}
+	return &gnapi.AggregateSourceInfo{}, nil
+}
+
+func (a *Annotator) AnnotateTempoMessageEventsContext(ctx context.Context, isoformOverrideSource string, tm *tt.TempoMessage) (*gn.AnnotationReport, error) {
+	a.mu.Lock()
+	a.isoforms = append(a.isoforms, isoformOverrideSource)
+	a.mu.Unlock()
+
+	err := a.Err
+	select {
+	case <-time.After(a.Delay):
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	report := &gn.AnnotationReport{StatusCounts: make(map[gn.AnnotationStatusCode]int)}
+	for _, e := range tm.Events {
+		status := gn.AnnotationStatus{Code: gn.StatusTransportError}
+		if err == nil {
+			e.HugoSymbol = a.HugoSymbol
+			if e.HugoSymbol == "" {
+				e.HugoSymbol = tm.CmoSampleId
+			}
+			status.Code = gn.StatusSuccess
+		}
+		e.AnnotationStatus = status.String()
+		report.Statuses = append(report.Statuses, status)
+		report.StatusCounts[status.Code]++
+	}
+	return report, err
+}
+
+func (a *Annotator) AnnotateStream(ctx context.Context, isoformOverrideSource string, in <-chan *tt.TempoMessage) <-chan gn.StreamResult {
+	out := make(chan gn.StreamResult)
+	go func() {
+		defer close(out)
+		for tm := range in {
+			report, err := a.AnnotateTempoMessageEventsContext(ctx, isoformOverrideSource, tm)
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case out <- gn.StreamResult{Message: tm, Report: report, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
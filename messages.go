@@ -0,0 +1,106 @@
+package genome_nexus_annotator_go
+
+import (
+	"errors"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// MessagesReport summarizes annotating many TempoMessages together.
+type MessagesReport struct {
+	// Reports holds one report per TempoMessage, in the same order as the messages.
+	Reports []*AnnotationReport `json:"reports"`
+	// Errors holds the Genome Nexus request errors affecting each TempoMessage,
+	// in the same order as the messages; nil when none of its events failed to send.
+	Errors []error `json:"-"`
+	// TotalEvents is the number of events across all messages.
+	TotalEvents int `json:"totalEvents"`
+	// UniqueQueryKeys is the number of distinct genomic locations sent to Genome Nexus.
+	UniqueQueryKeys int `json:"uniqueQueryKeys"`
+	// DeduplicatedEvents is the number of queried events that did not need their own request.
+	DeduplicatedEvents int `json:"deduplicatedEvents"`
+	// CrossMessageQueryKeys is the number of genomic locations shared by more than one message.
+	CrossMessageQueryKeys int           `json:"crossMessageQueryKeys"`
+	Batches               []BatchTiming `json:"batches,omitempty"`
+	Retries               int           `json:"retries"`
+	CacheHits             int           `json:"cacheHits,omitempty"`
+	// DryRun holds the request that would have been sent in dry-run mode. Its
+	// event indices refer to the events of all messages, in message order.
+	DryRun *DryRunPreview `json:"dryRun,omitempty"`
+}
+
+// AnnotateTempoMessages annotates the events of every TempoMessage in place,
+// sending each distinct genomic location across all messages to Genome Nexus
+// once. Failures are attributed to the messages whose events they affect.
+func (gn GNAnnotatorService) AnnotateTempoMessages(
+	isoformOverrideSource string,
+	tms []*tt.TempoMessage,
+) (*MessagesReport, error) {
+	events := make([]*tt.Event, 0)
+	for _, tm := range tms {
+		events = append(events, tm.Events...)
+	}
+	combined, err := gn.annotateEvents(isoformOverrideSource, events)
+
+	mr := splitAnnotationReport(combined, tms)
+	for i, tm := range tms {
+		gn.applyTumorType(tm, mr.Reports[i])
+	}
+	mr.Batches = combined.Batches
+	mr.Retries = combined.Retries
+	mr.CacheHits = combined.CacheHits
+	mr.DryRun = combined.DryRun
+	return mr, err
+}
+
+// splitAnnotationReport divides a report over the concatenated events of tms
+// into one report per message and computes the deduplication statistics.
+func splitAnnotationReport(combined *AnnotationReport, tms []*tt.TempoMessage) *MessagesReport {
+	mr := &MessagesReport{
+		Reports:         make([]*AnnotationReport, len(tms)),
+		Errors:          make([]error, len(tms)),
+		TotalEvents:     len(combined.Statuses),
+		UniqueQueryKeys: combined.UniqueQueryKeys,
+	}
+	messagesByKey := make(map[string]map[int]bool)
+	offset := 0
+	for i, tm := range tms {
+		report := newAnnotationReport(0)
+		report.Statuses = combined.Statuses[offset : offset+len(tm.Events)]
+		if combined.Enrichments != nil {
+			report.Enrichments = combined.Enrichments[offset : offset+len(tm.Events)]
+		}
+		if combined.Filters != nil {
+			report.Filters = combined.Filters[offset : offset+len(tm.Events)]
+		}
+		report.Provenance = combined.Provenance
+		report.tally()
+		mr.Reports[i] = report
+		offset += len(tm.Events)
+
+		seenErrors := make(map[string]bool)
+		var errs []error
+		for _, s := range report.Statuses {
+			if !s.wasQueried() {
+				continue
+			}
+			mr.DeduplicatedEvents++
+			if messagesByKey[s.QueryKey] == nil {
+				messagesByKey[s.QueryKey] = make(map[int]bool)
+			}
+			messagesByKey[s.QueryKey][i] = true
+			if s.Code == StatusTransportError && !seenErrors[s.ErrorMessage] {
+				seenErrors[s.ErrorMessage] = true
+				errs = append(errs, errors.New(s.ErrorMessage))
+			}
+		}
+		mr.Errors[i] = errors.Join(errs...)
+	}
+	mr.DeduplicatedEvents -= mr.UniqueQueryKeys
+	for _, messages := range messagesByKey {
+		if len(messages) > 1 {
+			mr.CrossMessageQueryKeys++
+		}
+	}
+	return mr
+}
@@ -0,0 +1,108 @@
+package genome_nexus_annotator_go
+
+import (
+	"strconv"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// ReadCounts are the reference and alternate read counts of a sample.
+type ReadCounts struct {
+	Ref int `json:"ref"`
+	Alt int `json:"alt"`
+	// Valid is false when the counts are missing or cannot be parsed.
+	Valid bool `json:"valid"`
+}
+
+// Depth returns the number of reads covering the variant.
+func (c ReadCounts) Depth() int {
+	return c.Ref + c.Alt
+}
+
+// VAF returns the variant allele frequency, 0 when no read covers the variant.
+func (c ReadCounts) VAF() float64 {
+	if c.Depth() <= 0 {
+		return 0
+	}
+	return float64(c.Alt) / float64(c.Depth())
+}
+
+// EventMetrics holds the read counts of the tumor and matched normal samples
+// of an event.
+type EventMetrics struct {
+	Tumor  ReadCounts `json:"tumor"`
+	Normal ReadCounts `json:"normal"`
+}
+
+// ComputeEventMetrics parses the tumor and normal read counts of e.
+func ComputeEventMetrics(e *tt.Event) EventMetrics {
+	return EventMetrics{
+		Tumor:  parseReadCounts(e.TRefCount, e.TAltCount),
+		Normal: parseReadCounts(e.NRefCount, e.NAltCount),
+	}
+}
+
+// parseReadCounts parses MAF read counts, which may be empty, NA or "." when
+// unknown and are sometimes written as floats such as "12.0".
+func parseReadCounts(ref, alt string) ReadCounts {
+	refCount, ok := parseReadCount(ref)
+	if !ok {
+		return ReadCounts{}
+	}
+	altCount, ok := parseReadCount(alt)
+	if !ok {
+		return ReadCounts{}
+	}
+	return ReadCounts{Ref: refCount, Alt: altCount, Valid: true}
+}
+
+func parseReadCount(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, n >= 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 || f != float64(int(f)) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// ReadCountFilter flags events with too little tumor read support, or too much
+// support in the matched normal, as artifacts. A zero threshold disables its
+// check and events without read counts pass.
+type ReadCountFilter struct {
+	MinDepth    int
+	MinAltCount int
+	MinVAF      float64
+	// MaxNormalVAF and MaxNormalAltCount are the highest variant allele
+	// frequency and alternate read count of the matched normal.
+	MaxNormalVAF      float64
+	MaxNormalAltCount int
+}
+
+// FilterEvent implements AnnotationFilter.
+func (f *ReadCountFilter) FilterEvent(e *tt.Event, _ *EventEnrichment) *FilterResult {
+	m := ComputeEventMetrics(e)
+	result := &FilterResult{Verdict: FilterArtifact}
+	if m.Tumor.Valid {
+		if f.MinDepth > 0 && m.Tumor.Depth() < f.MinDepth {
+			result.Reasons = append(result.Reasons, ReasonLowDepth)
+		}
+		if f.MinAltCount > 0 && m.Tumor.Alt < f.MinAltCount {
+			result.Reasons = append(result.Reasons, ReasonLowAltCount)
+		}
+		if f.MinVAF > 0 && m.Tumor.VAF() < f.MinVAF {
+			result.Reasons = append(result.Reasons, ReasonLowVAF)
+		}
+	}
+	if m.Normal.Valid && ((f.MaxNormalVAF > 0 && m.Normal.VAF() > f.MaxNormalVAF) ||
+		(f.MaxNormalAltCount > 0 && m.Normal.Alt > f.MaxNormalAltCount)) {
+		result.Reasons = append(result.Reasons, ReasonNormalContamination)
+	}
+	if len(result.Reasons) == 0 {
+		return nil
+	}
+	return result
+}
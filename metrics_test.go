@@ -0,0 +1,27 @@
+package genome_nexus_annotator_go
+
+import (
+	"testing"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func TestComputeEventMetrics(t *testing.T) {
+	tests := []struct {
+		tRef, tAlt, nRef, nAlt string
+		tumor, normal          ReadCounts
+		tumorVAF               float64
+	}{
+		{"75", "25", "40", "0", ReadCounts{75, 25, true}, ReadCounts{40, 0, true}, 0.25},
+		{" 30 ", "10.0", "", "", ReadCounts{30, 10, true}, ReadCounts{}, 0.25},
+		{"NA", "12", ".", ".", ReadCounts{}, ReadCounts{}, 0},
+		{"0", "0", "-1", "3", ReadCounts{0, 0, true}, ReadCounts{}, 0},
+		{"10", "2.5", "NaN", "1", ReadCounts{}, ReadCounts{}, 0},
+	}
+	for _, tc := range tests {
+		m := ComputeEventMetrics(&tt.Event{TRefCount: tc.tRef, TAltCount: tc.tAlt, NRefCount: tc.nRef, NAltCount: tc.nAlt})
+		if m.Tumor != tc.tumor || m.Normal != tc.normal || m.Tumor.VAF() != tc.tumorVAF {
+			t.Errorf("%q/%q %q/%q: expected %+v %+v but got %+v", tc.tRef, tc.tAlt, tc.nRef, tc.nAlt, tc.tumor, tc.normal, m)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package genome_nexus_annotator_go
+
+import (
+	"strings"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// NucleotideContextEnrichment is the trinucleotide context of an SNV.
+type NucleotideContextEnrichment struct {
+	// Context is the reference base with its 5' and 3' neighbours on the
+	// forward strand, e.g. "ACC".
+	Context string `json:"context"`
+	// SBS96 is the single base substitution channel of the SNV, collapsed to
+	// the pyrimidine reference, e.g. "A[C>T]C".
+	SBS96 string `json:"sbs96,omitempty"`
+}
+
+// resolveNucleotideContext returns the trinucleotide context of an SNV, or nil
+// for other variants and when Genome Nexus returned no context.
+func resolveNucleotideContext(gnResponse gnapi.VariantAnnotation, event *tt.Event) *NucleotideContextEnrichment {
+	if gnResponse.NucleotideContext == nil || gnResponse.NucleotideContext.Annotation == nil ||
+		gnResponse.NucleotideContext.Annotation.Seq == nil {
+		return nil
+	}
+	referenceAllele := strings.ToUpper(resolveReferenceAllele(gnResponse, *event))
+	tumorSeqAllele := strings.ToUpper(resolveTumorSeqAllele(gnResponse, *event))
+	context := strings.ToUpper(*gnResponse.NucleotideContext.Annotation.Seq)
+	if len(referenceAllele) != 1 || len(tumorSeqAllele) != 1 || len(context) != 3 || context[1] != referenceAllele[0] {
+		return nil
+	}
+	return &NucleotideContextEnrichment{
+		Context: context,
+		SBS96:   sbs96Channel(context, tumorSeqAllele),
+	}
+}
+
+// sbs96Channel returns the SBS96 channel of the substitution of the middle base
+// of context by alt, reverse complemented when the reference is a purine.
+// It returns "" when a base is not one of ACGT.
+func sbs96Channel(context, alt string) string {
+	if strings.Trim(context+alt, "ACGT") != "" || context[1] == alt[0] {
+		return ""
+	}
+	if context[1] == 'A' || context[1] == 'G' {
+		context = reverseComplement(context)
+		alt = reverseComplement(alt)
+	}
+	return context[:1] + "[" + context[1:2] + ">" + alt + "]" + context[2:]
+}
+
+func reverseComplement(seq string) string {
+	b := make([]byte, len(seq))
+	for i := range seq {
+		var c byte
+		switch seq[len(seq)-1-i] {
+		case 'A':
+			c = 'T'
+		case 'C':
+			c = 'G'
+		case 'G':
+			c = 'C'
+		case 'T':
+			c = 'A'
+		default:
+			c = 'N'
+		}
+		b[i] = c
+	}
+	return string(b)
+}
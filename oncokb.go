@@ -0,0 +1,193 @@
+package genome_nexus_annotator_go
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// TumorTypeFunc returns the OncoTree code or name of the tumor of tm, used to
+// resolve the OncoKB levels of its events. It returns "" when unknown.
+type TumorTypeFunc func(tm *tt.TempoMessage) string
+
+// OncoKB therapeutic levels, from the highest to the lowest.
+var (
+	oncokbSensitiveLevels  = []string{"LEVEL_1", "LEVEL_2", "LEVEL_3A", "LEVEL_3B", "LEVEL_4"}
+	oncokbResistanceLevels = []string{"LEVEL_R1", "LEVEL_R2"}
+)
+
+// OncokbEnrichment is the OncoKB annotation of a variant.
+type OncokbEnrichment struct {
+	// Oncogenic is the oncogenicity, e.g. "Oncogenic" or "Likely Neutral".
+	Oncogenic      string `json:"oncogenic,omitempty"`
+	MutationEffect string `json:"mutationEffect,omitempty"`
+	// HighestSensitiveLevel and HighestResistanceLevel are the highest levels
+	// across all tumor types.
+	HighestSensitiveLevel  string            `json:"highestSensitiveLevel,omitempty"`
+	HighestResistanceLevel string            `json:"highestResistanceLevel,omitempty"`
+	Treatments             []OncokbTreatment `json:"treatments,omitempty"`
+	// TumorType is the tumor type of the message, as returned by the
+	// TumorTypeFunc set with WithTumorType.
+	TumorType string `json:"tumorType,omitempty"`
+	// TumorTypeSensitiveLevel and TumorTypeResistanceLevel are the highest
+	// levels of the treatments associated with TumorType.
+	TumorTypeSensitiveLevel  string `json:"tumorTypeSensitiveLevel,omitempty"`
+	TumorTypeResistanceLevel string `json:"tumorTypeResistanceLevel,omitempty"`
+}
+
+// OncokbTreatment is the level of evidence of a treatment in a cancer type.
+type OncokbTreatment struct {
+	Level          string `json:"level"`
+	CancerTypeCode string `json:"cancerTypeCode,omitempty"`
+	CancerType     string `json:"cancerType,omitempty"`
+	MainType       string `json:"mainType,omitempty"`
+}
+
+// IsOncogenic reports whether OncoKB considers the variant oncogenic or likely
+// oncogenic.
+func (o *OncokbEnrichment) IsOncogenic() bool {
+	if o == nil {
+		return false
+	}
+	oncogenic := strings.ToLower(o.Oncogenic)
+	return oncogenic == "oncogenic" || oncogenic == "likely oncogenic"
+}
+
+// resolveOncokb returns the OncoKB annotation of the variant, or nil when
+// Genome Nexus returned none, e.g. because no OncoKB token was given.
+func resolveOncokb(gnResponse gnapi.VariantAnnotation) *OncokbEnrichment {
+	if gnResponse.Oncokb == nil || gnResponse.Oncokb.Annotation == nil {
+		return nil
+	}
+	indicator := gnResponse.Oncokb.Annotation
+	enrichment := &OncokbEnrichment{}
+	if indicator.Oncogenic != nil {
+		enrichment.Oncogenic = *indicator.Oncogenic
+	}
+	if indicator.MutationEffect != nil && indicator.MutationEffect.KnownEffect != nil {
+		enrichment.MutationEffect = *indicator.MutationEffect.KnownEffect
+	}
+	if indicator.HighestSensitiveLevel != nil {
+		enrichment.HighestSensitiveLevel = *indicator.HighestSensitiveLevel
+	}
+	if indicator.HighestResistanceLevel != nil {
+		enrichment.HighestResistanceLevel = *indicator.HighestResistanceLevel
+	}
+	for _, t := range indicator.Treatments {
+		treatment := OncokbTreatment{}
+		if t.Level != nil {
+			treatment.Level = *t.Level
+		}
+		if ct := t.LevelAssociatedCancerType; ct != nil {
+			if ct.Code != nil {
+				treatment.CancerTypeCode = *ct.Code
+			}
+			if ct.Name != nil {
+				treatment.CancerType = *ct.Name
+			}
+			if ct.MainType != nil {
+				treatment.MainType = *ct.MainType
+			}
+		}
+		if !slices.Contains(enrichment.Treatments, treatment) {
+			enrichment.Treatments = append(enrichment.Treatments, treatment)
+		}
+	}
+	return enrichment
+}
+
+// matches reports whether the treatment is associated with tumorType, given as
+// an OncoTree code, a cancer type or a main type.
+func (t OncokbTreatment) matches(tumorType string) bool {
+	return tumorType != "" && (strings.EqualFold(t.CancerTypeCode, tumorType) ||
+		strings.EqualFold(t.CancerType, tumorType) ||
+		strings.EqualFold(t.MainType, tumorType))
+}
+
+// setTumorType resolves the levels of the treatments associated with tumorType.
+func (o *OncokbEnrichment) setTumorType(tumorType string) {
+	o.TumorType = tumorType
+	o.TumorTypeSensitiveLevel, o.TumorTypeResistanceLevel = "", ""
+	for _, t := range o.Treatments {
+		if !t.matches(tumorType) {
+			continue
+		}
+		o.TumorTypeSensitiveLevel = highestLevel(oncokbSensitiveLevels, o.TumorTypeSensitiveLevel, t.Level)
+		o.TumorTypeResistanceLevel = highestLevel(oncokbResistanceLevels, o.TumorTypeResistanceLevel, t.Level)
+	}
+}
+
+// highestLevel returns the higher of current and level in levels, ignoring
+// level when it is not one of levels.
+func highestLevel(levels []string, current, level string) string {
+	i := slices.Index(levels, level)
+	if i < 0 {
+		return current
+	}
+	if j := slices.Index(levels, current); j >= 0 && j <= i {
+		return current
+	}
+	return level
+}
+
+// applyTumorType resolves the tumor type specific OncoKB levels of the events of
+// tm, whose enrichments are in report.
+func (gn GNAnnotatorService) applyTumorType(tm *tt.TempoMessage, report *AnnotationReport) {
+	if gn.tumorType == nil || report == nil {
+		return
+	}
+	tumorType := gn.tumorType(tm)
+	for _, e := range report.Enrichments {
+		if e != nil && e.Oncokb != nil {
+			e.Oncokb.setTumorType(tumorType)
+		}
+	}
+}
+
+// addOncokbToken adds oncokbToken to token, the JSON map of tokens by source
+// sent to Genome Nexus.
+func addOncokbToken(token, oncokbToken string) (string, error) {
+	tokens := make(map[string]string)
+	if token != "" {
+		if err := json.Unmarshal([]byte(token), &tokens); err != nil {
+			// not wrapping err, it could quote the token
+			return "", errors.New("the token must be a JSON map of tokens by source to add the OncoKB token")
+		}
+	}
+	tokens["oncokb"] = oncokbToken
+	b, err := json.Marshal(tokens)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// redactTokens removes the tokens from err, since errors returned by the HTTP
+// client quote the request URL.
+func (gn GNAnnotatorService) redactTokens(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if redacted := gn.redactTokenString(msg); redacted != msg {
+		return errors.New(redacted)
+	}
+	return err
+}
+
+// redactTokenString replaces the tokens in s, as is or query escaped.
+func (gn GNAnnotatorService) redactTokenString(s string) string {
+	for _, token := range []string{gn.token, gn.oncokbToken} {
+		if token == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, url.QueryEscape(token), redactedToken)
+		s = strings.ReplaceAll(s, token, redactedToken)
+	}
+	return s
+}
@@ -0,0 +1,191 @@
+package genome_nexus_annotator_go
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Option configures optional behaviour of a GNAnnotatorService.
+type Option func(*GNAnnotatorService)
+
+// WithProteinPositionRange makes the annotator write ProteinPosition as a
+// start-end range (e.g. "4-11") when the variant spans more than one residue.
+// By default only the start position is written, matching the Java pipeline.
+func WithProteinPositionRange(enabled bool) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.proteinPositionRange = enabled
+	}
+}
+
+// WithBatchSize sets the maximum number of genomic locations sent to Genome
+// Nexus in a single request.
+func WithBatchSize(size int) Option {
+	return func(gn *GNAnnotatorService) {
+		if size > 0 {
+			gn.batchSize = size
+		}
+	}
+}
+
+// WithRetries retries failed Genome Nexus requests up to maxRetries times,
+// waiting backoff times the attempt number between attempts.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.maxRetries = max(maxRetries, 0)
+		gn.retryBackoff = backoff
+	}
+}
+
+// WithReferenceCheck verifies the reference allele of every event against
+// reference before it is annotated, handling mismatches according to mode.
+func WithReferenceCheck(reference ReferenceGenome, mode ReferenceCheckMode) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.reference = reference
+		gn.referenceCheckMode = mode
+	}
+}
+
+// WithDryRun makes every annotation prepare the Genome Nexus request without
+// sending it. Reports then hold the request in DryRun and events are left
+// untouched. DryRunTempoMessageEvents does the same for a single call.
+func WithDryRun(enabled bool) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.dryRun = enabled
+	}
+}
+
+// WithEnrichmentFields sets the Genome Nexus fields requested for every
+// variant. annotation_summary is always requested since the annotated event
+// fields are read from it. Fields of an Enrichment, such as hotspots or
+// signal, also request that enrichment.
+func WithEnrichmentFields(fields ...string) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.fields = []string{"annotation_summary"}
+		for _, f := range fields {
+			if f = strings.TrimSpace(f); f != "" && !slices.Contains(gn.fields, f) {
+				gn.fields = append(gn.fields, f)
+			}
+		}
+	}
+}
+
+// WithEnrichments requests optional annotations from Genome Nexus and
+// returns them in AnnotationReport.Enrichments.
+func WithEnrichments(enrichments ...Enrichment) Option {
+	return func(gn *GNAnnotatorService) {
+		for _, e := range enrichments {
+			if !slices.Contains(gn.enrichments, e) {
+				gn.enrichments = append(gn.enrichments, e)
+			}
+		}
+	}
+}
+
+// WithOncokbToken passes an OncoKB token to Genome Nexus, which then proxies
+// OncoKB, and requests the oncokb enrichment. The token given to
+// NewGNAnnotatorService must then be empty or a JSON map of tokens by source.
+func WithOncokbToken(token string) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.oncokbToken = token
+		if token != "" && !slices.Contains(gn.enrichments, EnrichmentOncokb) {
+			gn.enrichments = append(gn.enrichments, EnrichmentOncokb)
+		}
+	}
+}
+
+// WithTumorType sets the function returning the tumor type of each message,
+// used to resolve the tumor type specific OncoKB levels.
+func WithTumorType(tumorType TumorTypeFunc) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.tumorType = tumorType
+	}
+}
+
+// WithPtmWindow sets the number of residues on each side of the protein
+// position searched for PTM sites by the protein enrichment.
+func WithPtmWindow(residues int) Option {
+	return func(gn *GNAnnotatorService) {
+		if residues >= 0 {
+			gn.ptmWindow = residues
+		}
+	}
+}
+
+// WithGnomadPreference sets the gnomAD data sets the event gnomAD allele
+// frequencies are read from, in order of preference: the first one with data
+// for the variant is used. By default only the exome data set is read.
+func WithGnomadPreference(sources ...GnomadSource) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.gnomadPreference = slices.Clone(sources)
+	}
+}
+
+// WithFilters runs filters on every successfully annotated event and records
+// their verdicts in AnnotationReport.Filters.
+func WithFilters(filters ...AnnotationFilter) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.filters = append(gn.filters, filters...)
+	}
+}
+
+// WithConcurrency sets how many batched requests are sent to Genome Nexus at
+// the same time.
+func WithConcurrency(n int) Option {
+	return func(gn *GNAnnotatorService) {
+		if n > 0 {
+			gn.concurrency = n
+		}
+	}
+}
+
+const (
+	// StripMatchingBasesAll removes every allele base shared by the reference
+	// and tumor alleles. This is the default.
+	StripMatchingBasesAll = "all"
+	// StripMatchingBasesFirst only removes a shared first base.
+	StripMatchingBasesFirst = "first"
+	// StripMatchingBasesNone keeps the alleles as they were given.
+	StripMatchingBasesNone = "none"
+)
+
+// WithStripMatchingBases sets how allele bases shared by the reference and
+// tumor alleles are written back to events, like the -s option of the Java
+// pipeline.
+func WithStripMatchingBases(mode string) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.stripMatchingBases = mode
+	}
+}
+
+// WithCache answers genomic locations from cache before sending them to Genome
+// Nexus and stores successful annotations in it. The cache is not used while
+// OncoKB annotations are requested.
+func WithCache(cache AnnotationCache) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.cache = cache
+	}
+}
+
+// WithHTTPClient sends Genome Nexus requests with client, e.g. to tune its
+// connection pool or timeouts.
+func WithHTTPClient(client *http.Client) Option {
+	return func(gn *GNAnnotatorService) {
+		gn.client.GetConfig().HTTPClient = client
+	}
+}
+
+// WithStreamWindow sets how AnnotateStream micro-batches messages: a batch is
+// annotated once it holds at least maxEvents events or maxWait has elapsed
+// since its first message arrived.
+func WithStreamWindow(maxEvents int, maxWait time.Duration) Option {
+	return func(gn *GNAnnotatorService) {
+		if maxEvents > 0 {
+			gn.streamMaxEvents = maxEvents
+		}
+		if maxWait > 0 {
+			gn.streamWindow = maxWait
+		}
+	}
+}
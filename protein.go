@@ -0,0 +1,121 @@
+package genome_nexus_annotator_go
+
+import (
+	"slices"
+	"strings"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+// defaultPtmWindow is the number of residues on each side of the protein
+// position searched for PTM sites.
+const defaultPtmWindow = 5
+
+// ProteinEnrichment is the protein level annotation of a variant on the
+// canonical transcript.
+type ProteinEnrichment struct {
+	ProteinStart int `json:"proteinStart,omitempty"`
+	ProteinEnd   int `json:"proteinEnd,omitempty"`
+	// Domains are the protein domains overlapping the variant, as reported by
+	// VEP for the canonical transcript.
+	Domains []ProteinDomain `json:"domains,omitempty"`
+	// Ptms are the post-translational modification sites within the PTM
+	// window of the protein position, see WithPtmWindow.
+	Ptms []PtmSite `json:"ptms,omitempty"`
+}
+
+// ProteinDomain is a protein domain, e.g. Source "Pfam" and Id "PF07714".
+type ProteinDomain struct {
+	Source string `json:"source"`
+	Id     string `json:"id"`
+}
+
+// PtmSite is a post-translational modification site.
+type PtmSite struct {
+	Type             string   `json:"type"`
+	Position         int      `json:"position"`
+	Sequence         string   `json:"sequence,omitempty"`
+	UniprotAccession string   `json:"uniprotAccession,omitempty"`
+	PubmedIds        []string `json:"pubmedIds,omitempty"`
+}
+
+// PfamDomains returns the PFAM domains overlapping the variant.
+func (p *ProteinEnrichment) PfamDomains() []ProteinDomain {
+	if p == nil {
+		return nil
+	}
+	pfam := make([]ProteinDomain, 0)
+	for _, d := range p.Domains {
+		if isPfamSource(d.Source) {
+			pfam = append(pfam, d)
+		}
+	}
+	return pfam
+}
+
+// isPfamSource reports whether a VEP domain source is PFAM, named "Pfam" since
+// VEP 104 and "Pfam_domain" before.
+func isPfamSource(source string) bool {
+	return strings.EqualFold(source, "Pfam") || strings.EqualFold(source, "Pfam_domain")
+}
+
+// parseVepDomains converts the domains VEP reports for a transcript.
+func parseVepDomains(domains []map[string]string) []ProteinDomain {
+	parsed := make([]ProteinDomain, 0, len(domains))
+	for _, d := range domains {
+		domain := ProteinDomain{Source: d["db"], Id: d["name"]}
+		if domain.Id == "" || slices.Contains(parsed, domain) {
+			continue
+		}
+		parsed = append(parsed, domain)
+	}
+	return parsed
+}
+
+// resolveProtein returns the domains and PTM sites around the protein position
+// of the canonical transcript, or nil when the variant does not change the
+// protein.
+func resolveProtein(gnResponse gnapi.VariantAnnotation, canonicalTranscript gnapi.TranscriptConsequenceSummary, ptmWindow int) *ProteinEnrichment {
+	if canonicalTranscript.ProteinPosition == nil || canonicalTranscript.ProteinPosition.Start == nil {
+		return nil
+	}
+	enrichment := &ProteinEnrichment{ProteinStart: int(*canonicalTranscript.ProteinPosition.Start)}
+	enrichment.ProteinEnd = enrichment.ProteinStart
+	if canonicalTranscript.ProteinPosition.End != nil && int(*canonicalTranscript.ProteinPosition.End) > enrichment.ProteinStart {
+		enrichment.ProteinEnd = int(*canonicalTranscript.ProteinPosition.End)
+	}
+	if rawTC := getCanonicalRawTranscript(gnResponse); rawTC != nil && len(rawTC.Domains) > 0 {
+		enrichment.Domains = parseVepDomains(rawTC.Domains)
+	}
+	if gnResponse.Ptms == nil {
+		return enrichment
+	}
+
+	transcriptId := resolveTranscriptId(canonicalTranscript)
+	for _, ptms := range gnResponse.Ptms.Annotation {
+		for _, p := range ptms {
+			if p.Position == nil || int(*p.Position) < enrichment.ProteinStart-ptmWindow || int(*p.Position) > enrichment.ProteinEnd+ptmWindow {
+				continue
+			}
+			if len(p.EnsemblTranscriptIds) > 0 && !slices.Contains(p.EnsemblTranscriptIds, transcriptId) {
+				continue
+			}
+			site := PtmSite{Position: int(*p.Position), PubmedIds: p.PubmedIds}
+			if p.Type != nil {
+				site.Type = *p.Type
+			}
+			if p.Sequence != nil {
+				site.Sequence = *p.Sequence
+			}
+			if p.UniprotAccession != nil {
+				site.UniprotAccession = *p.UniprotAccession
+			}
+			if !slices.ContainsFunc(enrichment.Ptms, func(s PtmSite) bool {
+				return s.Type == site.Type && s.Position == site.Position && s.UniprotAccession == site.UniprotAccession
+			}) {
+				enrichment.Ptms = append(enrichment.Ptms, site)
+			}
+		}
+	}
+	return enrichment
+}
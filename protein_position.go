@@ -0,0 +1,81 @@
+package genome_nexus_annotator_go
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+Utility functions for extracting protein positions from the different places
+they can be found: the Genome Nexus protein position range, HGVSp, HGVSp_Short
+and the ProteinPosition already present on the incoming event.
+*/
+
+var (
+	// matches the first (and optional second) residue position of a protein change, e.g.
+	// p.G382D, p.A4_P11dup, p.K10Rfs*5, p.*100Qext*10, p.X125_splice, p.Ala4_Pro11dup, p.(Gly12Val)
+	hgvspPositionRegex = regexp.MustCompile(`p\.\(?(?:[A-Z][a-z]{2}|[A-Z*])(\d+)(?:_(?:[A-Z][a-z]{2}|[A-Z*])(\d+))?`)
+	// matches VEP style protein positions, e.g. 4, 4-11, 4-11/156, ?-11/156
+	vepProteinPositionRegex = regexp.MustCompile(`^(\d+|\?)(?:-(\d+|\?))?(?:/\d+)?$`)
+)
+
+// proteinPosition is a resolved protein position range; zero values mean unknown.
+type proteinPosition struct {
+	start int
+	end   int
+}
+
+func (p proteinPosition) isEmpty() bool {
+	return p.start == 0 && p.end == 0
+}
+
+// format returns the start position, or the start-end range when writeRange is
+// set and the position spans more than one residue.
+func (p proteinPosition) format(writeRange bool) string {
+	if p.start == 0 {
+		if p.end == 0 {
+			return ""
+		}
+		return strconv.Itoa(p.end)
+	}
+	if writeRange && p.end > p.start {
+		return strconv.Itoa(p.start) + "-" + strconv.Itoa(p.end)
+	}
+	return strconv.Itoa(p.start)
+}
+
+// newProteinPosition normalizes a start/end pair so that a missing end equals start.
+func newProteinPosition(start, end int) proteinPosition {
+	if end == 0 || end < start {
+		end = start
+	}
+	return proteinPosition{start: start, end: end}
+}
+
+// parseHgvspPosition extracts the protein position from HGVSp or HGVSp_Short,
+// with or without a protein id prefix (e.g. ENSP00000372115.3:p.Gly382Asp).
+func parseHgvspPosition(hgvsp string) proteinPosition {
+	match := hgvspPositionRegex.FindStringSubmatch(hgvsp)
+	if match == nil {
+		return proteinPosition{}
+	}
+	start, _ := strconv.Atoi(match[1])
+	end, _ := strconv.Atoi(match[2])
+	return newProteinPosition(start, end)
+}
+
+// parseVepProteinPosition extracts the protein position from a VEP style
+// Protein_position value, as found on incoming events.
+func parseVepProteinPosition(position string) proteinPosition {
+	match := vepProteinPositionRegex.FindStringSubmatch(strings.TrimSpace(position))
+	if match == nil {
+		return proteinPosition{}
+	}
+	start, _ := strconv.Atoi(match[1])
+	end, _ := strconv.Atoi(match[2])
+	if start == 0 {
+		return proteinPosition{start: end, end: end}
+	}
+	return newProteinPosition(start, end)
+}
@@ -0,0 +1,94 @@
+package genome_nexus_annotator_go
+
+import (
+	"testing"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func TestParseHgvspPosition(t *testing.T) {
+	tests := []struct {
+		hgvsp string
+		start int
+		end   int
+	}{
+		{"p.G382D", 382, 382},
+		{"p.A4_P11dup", 4, 11},
+		{"p.A4dup", 4, 4},
+		{"p.K10Rfs*5", 10, 10},
+		{"p.K10fs", 10, 10},
+		{"p.*100Qext*10", 100, 100},
+		{"p.M1ext-5", 1, 1},
+		{"p.X125_splice", 125, 125},
+		{"p.G12_L13delinsVV", 12, 13},
+		{"p.V600=", 600, 600},
+		{"p.Ala4_Pro11dup", 4, 11},
+		{"p.Gly382Asp", 382, 382},
+		{"p.Ter100GlnextTer10", 100, 100},
+		{"ENSP00000372115.3:p.Gly382Asp", 382, 382},
+		{"p.(Gly12Val)", 12, 12},
+		{"p.=", 0, 0},
+		{"", 0, 0},
+	}
+	for _, test := range tests {
+		pos := parseHgvspPosition(test.hgvsp)
+		if pos.start != test.start || pos.end != test.end {
+			t.Errorf("hgvsp: %q; expected %d-%d but got %d-%d", test.hgvsp, test.start, test.end, pos.start, pos.end)
+		}
+	}
+}
+
+func TestParseVepProteinPosition(t *testing.T) {
+	tests := []struct {
+		position string
+		start    int
+		end      int
+	}{
+		{"4", 4, 4},
+		{"4-11", 4, 11},
+		{"4-11/156", 4, 11},
+		{"?-11/156", 11, 11},
+		{"4-?", 4, 4},
+		{"?", 0, 0},
+		{"abc", 0, 0},
+	}
+	for _, test := range tests {
+		pos := parseVepProteinPosition(test.position)
+		if pos.start != test.start || pos.end != test.end {
+			t.Errorf("position: %q; expected %d-%d but got %d-%d", test.position, test.start, test.end, pos.start, pos.end)
+		}
+	}
+}
+
+func TestResolveProteinPosition(t *testing.T) {
+	start, end := int32(4), int32(11)
+	hgvspShort := "p.A4_P11dup"
+	withRange := gnapi.TranscriptConsequenceSummary{
+		TranscriptId:    "ENST00000304494",
+		ProteinPosition: &gnapi.IntegerRange{Start: &start, End: &end},
+	}
+	withHgvspShort := gnapi.TranscriptConsequenceSummary{
+		TranscriptId: "ENST00000304494",
+		HgvspShort:   &hgvspShort,
+	}
+	tests := []struct {
+		name       string
+		transcript gnapi.TranscriptConsequenceSummary
+		event      *tt.Event
+		writeRange bool
+		expected   string
+	}{
+		{"range start only", withRange, &tt.Event{}, false, "4"},
+		{"range", withRange, &tt.Event{}, true, "4-11"},
+		{"hgvsp short", withHgvspShort, &tt.Event{}, true, "4-11"},
+		{"event fallback", gnapi.TranscriptConsequenceSummary{}, &tt.Event{ProteinPosition: "7-9/100"}, true, "7-9"},
+		{"nothing", gnapi.TranscriptConsequenceSummary{}, &tt.Event{}, true, ""},
+	}
+	for _, test := range tests {
+		got := resolveProteinPosition(test.transcript, test.event, test.writeRange)
+		if got != test.expected {
+			t.Errorf("%s: expected %q but got %q", test.name, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package genome_nexus_annotator_go
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+// Provenance records what produced a set of annotations.
+type Provenance struct {
+	GenomeNexusVersion      string    `json:"genomeNexusVersion,omitempty"`
+	VepVersion              string    `json:"vepVersion,omitempty"`
+	VepCacheVersion         string    `json:"vepCacheVersion,omitempty"`
+	MyVariantInfoVersion    string    `json:"myVariantInfoVersion,omitempty"`
+	MutationAssessorVersion string    `json:"mutationAssessorVersion,omitempty"`
+	IsoformOverrideSource   string    `json:"isoformOverrideSource,omitempty"`
+	EnrichmentFields        []string  `json:"enrichmentFields,omitempty"`
+	AnnotatedAt             time.Time `json:"annotatedAt"`
+}
+
+// AnnotationVersion identifies the Genome Nexus and VEP versions that produced
+// the annotations. Store it with the annotated message to later re-annotate it
+// with ReannotateTempoMessageEvents.
+func (p Provenance) AnnotationVersion() string {
+	return fmt.Sprintf("genome-nexus=%s;vep=%s;vep-cache=%s", p.GenomeNexusVersion, p.VepVersion, p.VepCacheVersion)
+}
+
+// genomeNexusInfoTTL is how long the Genome Nexus source info is reused, so a
+// long-running service notices Genome Nexus and VEP upgrades.
+const genomeNexusInfoTTL = 5 * time.Minute
+
+// infoCache holds the Genome Nexus source info for genomeNexusInfoTTL.
+type infoCache struct {
+	mu        sync.Mutex
+	info      *gnapi.AggregateSourceInfo
+	fetchedAt time.Time
+}
+
+// cachedGenomeNexusInfo returns the source info, fetching it on first use and
+// once it is older than genomeNexusInfoTTL.
+func (gn GNAnnotatorService) cachedGenomeNexusInfo() (*gnapi.AggregateSourceInfo, error) {
+	if gn.infoCache == nil {
+		return gn.GetGenomeNexusInfo()
+	}
+	gn.infoCache.mu.Lock()
+	defer gn.infoCache.mu.Unlock()
+	if gn.infoCache.info != nil && time.Since(gn.infoCache.fetchedAt) < genomeNexusInfoTTL {
+		return gn.infoCache.info, nil
+	}
+	info, err := gn.GetGenomeNexusInfo()
+	if err != nil {
+		return nil, err
+	}
+	gn.infoCache.info, gn.infoCache.fetchedAt = info, time.Now()
+	return info, nil
+}
+
+// RefreshGenomeNexusInfo fetches the Genome Nexus source info again and uses it
+// for the provenance of all following annotations.
+func (gn GNAnnotatorService) RefreshGenomeNexusInfo() (*gnapi.AggregateSourceInfo, error) {
+	info, err := gn.GetGenomeNexusInfo()
+	if err != nil {
+		return nil, err
+	}
+	if gn.infoCache != nil {
+		gn.infoCache.mu.Lock()
+		gn.infoCache.info, gn.infoCache.fetchedAt = info, time.Now()
+		gn.infoCache.mu.Unlock()
+	}
+	return info, nil
+}
+
+// getProvenance builds the provenance of an annotation run. Failing to fetch
+// the source info leaves the versions empty but does not fail the annotation;
+// dry runs do not fetch it at all.
+func (gn GNAnnotatorService) getProvenance(isoformOverrideSource string) Provenance {
+	if gn.dryRun {
+		return gn.newProvenance(isoformOverrideSource, nil)
+	}
+	info, _ := gn.cachedGenomeNexusInfo()
+	return gn.newProvenance(isoformOverrideSource, info)
+}
+
+// currentProvenance is the provenance of an annotation run with the source
+// info Genome Nexus serves now rather than the cached one, which may predate
+// an upgrade. The cached source info is refreshed along the way.
+func (gn GNAnnotatorService) currentProvenance(isoformOverrideSource string) Provenance {
+	if gn.dryRun {
+		return gn.newProvenance(isoformOverrideSource, nil)
+	}
+	info, _ := gn.RefreshGenomeNexusInfo()
+	return gn.newProvenance(isoformOverrideSource, info)
+}
+
+// newProvenance builds the provenance of an annotation run with the versions
+// of the Genome Nexus source info, left empty when info is nil.
+func (gn GNAnnotatorService) newProvenance(isoformOverrideSource string, info *gnapi.AggregateSourceInfo) Provenance {
+	p := Provenance{
+		IsoformOverrideSource: isoformOverrideSource,
+		EnrichmentFields:      gn.enrichmentFields(),
+		AnnotatedAt:           time.Now().UTC(),
+	}
+	if info == nil {
+		return p
+	}
+	if server := info.GetGenomeNexus().Server; server != nil && server.Version != nil {
+		p.GenomeNexusVersion = *server.Version
+	}
+	vep := info.GetVep()
+	if vep.Server != nil && vep.Server.Version != nil {
+		p.VepVersion = *vep.Server.Version
+	}
+	if vep.Cache != nil && vep.Cache.Version != nil {
+		p.VepCacheVersion = *vep.Cache.Version
+	}
+	for _, source := range info.GetAnnotationSourcesInfo() {
+		name := strings.ToLower(source.GetId() + " " + source.GetName())
+		switch {
+		case strings.Contains(name, "myvariantinfo") || strings.Contains(name, "my_variant_info"):
+			p.MyVariantInfoVersion = source.GetVersion()
+		case strings.Contains(name, "mutationassessor") || strings.Contains(name, "mutation_assessor"):
+			p.MutationAssessorVersion = source.GetVersion()
+		}
+	}
+	return p
+}
@@ -0,0 +1,86 @@
+package genome_nexus_annotator_go
+
+import (
+	"fmt"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// ReannotateTempoMessageEvents re-annotates tm, only sending events that were
+// not successfully annotated. recordedVersion is the AnnotationVersion of the
+// report from the run that produced tm's current annotations; when it is empty
+// or differs from CurrentAnnotationVersion every event is annotated again.
+// Events left untouched are reported as SKIPPED_CACHED and still go through
+// the filters set with WithFilters, without enrichments.
+func (gn GNAnnotatorService) ReannotateTempoMessageEvents(
+	isoformOverrideSource string,
+	tm *tt.TempoMessage,
+	recordedVersion string,
+) (*AnnotationReport, error) {
+	current := gn.currentProvenance(isoformOverrideSource)
+	if recordedVersion == "" || current.GenomeNexusVersion == "" || recordedVersion != current.AnnotationVersion() {
+		report, err := gn.annotateEvents(isoformOverrideSource, tm.Events)
+		gn.applyTumorType(tm, report)
+		return report, err
+	}
+
+	indices := make([]int, 0)
+	events := make([]*tt.Event, 0)
+	for i, e := range tm.Events {
+		if e.AnnotationStatus != string(StatusSuccess) {
+			indices = append(indices, i)
+			events = append(events, e)
+		}
+	}
+
+	report := newAnnotationReport(len(tm.Events))
+	for i, e := range tm.Events {
+		report.Statuses[i] = AnnotationStatus{
+			Code:     StatusSkippedCached,
+			Reason:   fmt.Sprintf("already annotated with %s", recordedVersion),
+			QueryKey: buildEventKey(e),
+		}
+	}
+	if len(gn.filters) > 0 {
+		report.Filters = make([]*FilterResult, len(tm.Events))
+		for i, e := range tm.Events {
+			if e.AnnotationStatus == string(StatusSuccess) {
+				report.Filters[i] = gn.filterEvent(e, nil)
+			}
+		}
+	}
+	var err error
+	if len(events) > 0 {
+		var partial *AnnotationReport
+		partial, err = gn.annotateEvents(isoformOverrideSource, events)
+		if partial.Enrichments != nil {
+			report.Enrichments = make([]*EventEnrichment, len(tm.Events))
+		}
+		for j, idx := range indices {
+			report.Statuses[idx] = partial.Statuses[j]
+			if partial.Enrichments != nil {
+				report.Enrichments[idx] = partial.Enrichments[j]
+			}
+			if partial.Filters != nil {
+				report.Filters[idx] = partial.Filters[j]
+			}
+		}
+		report.Batches = partial.Batches
+		report.Retries = partial.Retries
+		report.CacheHits = partial.CacheHits
+	}
+	report.Provenance = current
+	report.tally()
+	gn.applyTumorType(tm, report)
+	return report, err
+}
+
+// CurrentAnnotationVersion returns the AnnotationVersion of the Genome Nexus
+// server now, fetching its source info again.
+func (gn GNAnnotatorService) CurrentAnnotationVersion() (string, error) {
+	info, err := gn.RefreshGenomeNexusInfo()
+	if err != nil {
+		return "", err
+	}
+	return gn.newProvenance("", info).AnnotationVersion(), nil
+}
@@ -0,0 +1,179 @@
+package genome_nexus_annotator_go
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+/*
+Verification of event reference alleles against a local reference genome,
+done before genomic locations are built and sent to Genome Nexus.
+*/
+
+// ReferenceGenome returns the reference sequence for 1-based, inclusive coordinates.
+type ReferenceGenome interface {
+	Sequence(chromosome string, start, end int64) (string, error)
+}
+
+// ReferenceCheckMode controls what happens to events whose reference allele
+// does not match the reference genome.
+type ReferenceCheckMode int
+
+const (
+	// ReferenceCheckWarn records the mismatch in GenomicLocationExplanation and still annotates the event.
+	ReferenceCheckWarn ReferenceCheckMode = iota
+	// ReferenceCheckFail records the mismatch and marks the event REFERENCE_MISMATCH without annotating it.
+	ReferenceCheckFail
+)
+
+// IndexedFasta is a ReferenceGenome backed by a FASTA file and its samtools faidx (.fai) index.
+type IndexedFasta struct {
+	file  *os.File
+	index map[string]faiEntry
+}
+
+type faiEntry struct {
+	length    int64
+	offset    int64
+	lineBases int64
+	lineWidth int64
+}
+
+// OpenIndexedFasta opens the FASTA file at path, reading its index from path + ".fai".
+func OpenIndexedFasta(path string) (*IndexedFasta, error) {
+	index, err := readFaiIndex(path + ".fai")
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reference FASTA %q: %v", path, err)
+	}
+	return &IndexedFasta{file: file, index: index}, nil
+}
+
+func readFaiIndex(path string) (map[string]faiEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FASTA index %q: %v", path, err)
+	}
+	defer f.Close()
+
+	index := make(map[string]faiEntry)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 5 {
+			return nil, fmt.Errorf("FASTA index %q line %d: expected 5 columns but got %d", path, line, len(cols))
+		}
+		var entry faiEntry
+		values := []*int64{&entry.length, &entry.offset, &entry.lineBases, &entry.lineWidth}
+		for i, v := range values {
+			if *v, err = strconv.ParseInt(cols[i+1], 10, 64); err != nil {
+				return nil, fmt.Errorf("FASTA index %q line %d: %v", path, line, err)
+			}
+		}
+		if entry.lineBases <= 0 || entry.lineWidth < entry.lineBases {
+			return nil, fmt.Errorf("FASTA index %q line %d: invalid line bases %d and line width %d", path, line, entry.lineBases, entry.lineWidth)
+		}
+		index[cols[0]] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read FASTA index %q: %v", path, err)
+	}
+	return index, nil
+}
+
+// Close closes the underlying FASTA file.
+func (f *IndexedFasta) Close() error {
+	return f.file.Close()
+}
+
+// normalizeChromosome drops the chr prefix, in any case, and names the
+// mitochondrial chromosome MT, so that chromosomes named either way match.
+func normalizeChromosome(chromosome string) string {
+	name := chromosome
+	if len(name) > 3 && strings.EqualFold(name[:3], "chr") {
+		name = name[3:]
+	}
+	if strings.EqualFold(name, "M") || strings.EqualFold(name, "MT") {
+		return "MT"
+	}
+	return name
+}
+
+// lookup finds the index entry for chromosome, allowing for chr prefixes and M/MT naming differences.
+func (f *IndexedFasta) lookup(chromosome string) (faiEntry, bool) {
+	name := normalizeChromosome(chromosome)
+	candidates := []string{chromosome, name, "chr" + name}
+	if name == "MT" {
+		candidates = append(candidates, "chrM", "M", "chrMT")
+	}
+	for _, c := range candidates {
+		if entry, ok := f.index[c]; ok {
+			return entry, true
+		}
+	}
+	return faiEntry{}, false
+}
+
+// Sequence returns the upper-cased reference sequence of chromosome from start
+// to end, 1-based and inclusive.
+func (f *IndexedFasta) Sequence(chromosome string, start, end int64) (string, error) {
+	entry, ok := f.lookup(chromosome)
+	if !ok {
+		return "", fmt.Errorf("chromosome %q is not in the reference FASTA", chromosome)
+	}
+	if start < 1 || end < start || end > entry.length {
+		return "", fmt.Errorf("%s:%d-%d is outside of the reference sequence (length %d)", chromosome, start, end, entry.length)
+	}
+	fileOffset := func(pos int64) int64 {
+		return entry.offset + pos/entry.lineBases*entry.lineWidth + pos%entry.lineBases
+	}
+	from, to := fileOffset(start-1), fileOffset(end-1)
+	buf := make([]byte, to-from+1)
+	if _, err := f.file.ReadAt(buf, from); err != nil {
+		return "", fmt.Errorf("failed to read %s:%d-%d from the reference FASTA: %v", chromosome, start, end, err)
+	}
+	seq := strings.NewReplacer("\n", "", "\r", "").Replace(string(buf))
+	return strings.ToUpper(seq), nil
+}
+
+// verifyReferenceAllele compares the reference allele of e with the reference
+// genome, returning the explanation to record for a mismatch or lookup
+// failure. An error is returned only for mismatches in ReferenceCheckFail
+// mode. Insertions and events with unparsable positions are not checked, and
+// e is not modified.
+func (gn GNAnnotatorService) verifyReferenceAllele(e *tt.Event) (string, error) {
+	if gn.reference == nil || e.ReferenceAllele == "-" || e.ReferenceAllele == "" {
+		return "", nil
+	}
+	start, err := parsePosition("start position", e.StartPosition)
+	if err != nil {
+		return "", nil
+	}
+	end, err := parsePosition("end position", e.EndPosition)
+	if err != nil {
+		return "", nil
+	}
+	seq, err := gn.reference.Sequence(e.Chromosome, start, end)
+	if err != nil {
+		return fmt.Sprintf("Reference allele not verified: %v", err), nil
+	}
+	if strings.EqualFold(seq, e.ReferenceAllele) {
+		return "", nil
+	}
+	explanation := fmt.Sprintf(
+		"Reference allele mismatch: event has %s but the reference genome has %s at %s:%d-%d",
+		e.ReferenceAllele, seq, e.Chromosome, start, end,
+	)
+	if gn.referenceCheckMode == ReferenceCheckFail {
+		return explanation, fmt.Errorf("reference allele %s does not match reference genome %s", e.ReferenceAllele, seq)
+	}
+	return explanation, nil
+}
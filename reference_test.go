@@ -0,0 +1,159 @@
+package genome_nexus_annotator_go
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// writeIndexedFasta writes sequences as a FASTA file wrapped at lineBases
+// bases per line, together with its .fai index, and returns the FASTA path.
+func writeIndexedFasta(t testing.TB, lineBases int, sequences map[string]string, order []string) string {
+	path := filepath.Join(t.TempDir(), "ref.fa")
+	var fasta, fai strings.Builder
+	for _, name := range order {
+		seq := sequences[name]
+		fasta.WriteString(">" + name + "\n")
+		offset := fasta.Len()
+		for i := 0; i < len(seq); i += lineBases {
+			fasta.WriteString(seq[i:min(i+lineBases, len(seq))] + "\n")
+		}
+		fmt.Fprintf(&fai, "%s\t%d\t%d\t%d\t%d\n", name, len(seq), offset, lineBases, lineBases+1)
+	}
+	if err := os.WriteFile(path, []byte(fasta.String()), 0o644); err != nil {
+		t.Fatalf("failed to write FASTA: %v", err)
+	}
+	if err := os.WriteFile(path+".fai", []byte(fai.String()), 0o644); err != nil {
+		t.Fatalf("failed to write FASTA index: %v", err)
+	}
+	return path
+}
+
+func TestIndexedFastaSequence(t *testing.T) {
+	path := writeIndexedFasta(t, 10, map[string]string{
+		"1":    "ACGTACGTACGGGGGTTTTTCCCCCAAAAA",
+		"chrM": "GATCACAGGT",
+	}, []string{"1", "chrM"})
+	ref, err := OpenIndexedFasta(path)
+	if err != nil {
+		t.Fatalf("failed to open indexed FASTA: %v", err)
+	}
+	defer ref.Close()
+
+	tests := []struct {
+		chromosome string
+		start, end int64
+		expected   string
+	}{
+		{"1", 1, 1, "A"},
+		{"1", 9, 12, "ACGG"},
+		{"chr1", 26, 30, "AAAAA"},
+		{"MT", 1, 4, "GATC"},
+		{"Chr1", 1, 1, "A"},
+		{"chrm", 1, 4, "GATC"},
+	}
+	for _, test := range tests {
+		seq, err := ref.Sequence(test.chromosome, test.start, test.end)
+		if err != nil || seq != test.expected {
+			t.Errorf("%s:%d-%d: expected %q but got %q (err: %v)", test.chromosome, test.start, test.end, test.expected, seq, err)
+		}
+	}
+	if _, err := ref.Sequence("2", 1, 1); err == nil {
+		t.Errorf("expected an error for a chromosome missing from the FASTA")
+	}
+	if _, err := ref.Sequence("1", 25, 31); err == nil {
+		t.Errorf("expected an error for a range past the end of the chromosome")
+	}
+}
+
+func TestNormalizeChromosome(t *testing.T) {
+	tests := map[string]string{
+		"1": "1", "chr1": "1", "CHR1": "1", "Chr1": "1",
+		"M": "MT", "chrM": "MT", "MT": "MT", "chrMT": "MT",
+		"X": "X", "GL000220.1": "GL000220.1", "chrUn_gl000220": "Un_gl000220",
+	}
+	for input, expected := range tests {
+		if got := normalizeChromosome(input); got != expected {
+			t.Errorf("%q: expected %q but got %q", input, expected, got)
+		}
+	}
+}
+
+func TestReadFaiIndexMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		fai  string
+	}{
+		{"missing columns", "1\t30\t3\t10\n"},
+		{"non-numeric offset", "1\t30\tx\t10\t11\n"},
+		{"zero line bases", "1\t30\t3\t0\t1\n"},
+		{"line width below line bases", "1\t30\t3\t10\t9\n"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "ref.fa")
+		if err := os.WriteFile(path+".fai", []byte(test.fai), 0o644); err != nil {
+			t.Fatalf("failed to write FASTA index: %v", err)
+		}
+		if _, err := OpenIndexedFasta(path); err == nil {
+			t.Errorf("%s: expected an error for a malformed index", test.name)
+		}
+	}
+}
+
+func TestAnnotateWithReferenceCheck(t *testing.T) {
+	path := writeIndexedFasta(t, 10, map[string]string{"1": "ACGTACGTACGGGGGTTTTT"}, []string{"1"})
+	ref, err := OpenIndexedFasta(path)
+	if err != nil {
+		t.Fatalf("failed to open indexed FASTA: %v", err)
+	}
+	defer ref.Close()
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "MUTYH"), true
+	})
+	newMessage := func() *tt.TempoMessage {
+		return &tt.TempoMessage{Events: []*tt.Event{
+			newTestEvent("1", "9", "12", "ACGG", "-"),
+			newTestEvent("1", "1", "1", "T", "G"),
+			newTestEvent("1", "1", "2", "-", "G"),
+		}}
+	}
+
+	tests := []struct {
+		mode     ReferenceCheckMode
+		expected []AnnotationStatusCode
+	}{
+		{ReferenceCheckWarn, []AnnotationStatusCode{StatusSuccess, StatusSuccess, StatusSuccess}},
+		{ReferenceCheckFail, []AnnotationStatusCode{StatusSuccess, StatusReferenceMismatch, StatusSuccess}},
+	}
+	for _, test := range tests {
+		gn, err := NewGNAnnotatorService(context.Background(), "", server.URL, WithReferenceCheck(ref, test.mode))
+		if err != nil {
+			t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+		}
+		tm := newMessage()
+		report, err := gn.AnnotateTempoMessageEvents(isoformOverrideString, tm)
+		if err != nil {
+			t.Fatalf("AnnotateTempoMessageEvents failed: %v", err)
+		}
+		for i, code := range test.expected {
+			if report.Statuses[i].Code != code {
+				t.Errorf("mode %d event %d: expected status %q but got %q", test.mode, i, code, report.Statuses[i].Code)
+			}
+		}
+		if tm.Events[0].GenomicLocationExplanation != "" || tm.Events[2].GenomicLocationExplanation != "" {
+			t.Errorf("mode %d: expected no explanation for the matching reference and the insertion", test.mode)
+		}
+		if !strings.HasPrefix(tm.Events[1].GenomicLocationExplanation, "Reference allele mismatch") {
+			t.Errorf("mode %d: expected the mismatch to be recorded but got %q", test.mode, tm.Events[1].GenomicLocationExplanation)
+		}
+		if annotated := tm.Events[1].HugoSymbol == "MUTYH"; annotated != (test.mode == ReferenceCheckWarn) {
+			t.Errorf("mode %d: unexpected annotation of the mismatched event %+v", test.mode, tm.Events[1])
+		}
+	}
+}
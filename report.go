@@ -0,0 +1,89 @@
+package genome_nexus_annotator_go
+
+import (
+	"time"
+)
+
+// AnnotationReport summarizes the outcome of annotating the events of a TempoMessage.
+type AnnotationReport struct {
+	// Statuses holds the status of every event, in the same order as the events.
+	Statuses     []AnnotationStatus           `json:"statuses"`
+	StatusCounts map[AnnotationStatusCode]int `json:"statusCounts"`
+	// FailedQueryKeys lists the genomic location keys of events that were not annotated.
+	FailedQueryKeys []string `json:"failedQueryKeys,omitempty"`
+	// UniqueQueryKeys is the number of distinct genomic locations sent to Genome Nexus.
+	UniqueQueryKeys int `json:"uniqueQueryKeys"`
+	// SharedKeyEvents is the number of events whose genomic location key is shared with another event.
+	SharedKeyEvents int           `json:"sharedKeyEvents"`
+	Batches         []BatchTiming `json:"batches,omitempty"`
+	// Retries is the total number of retried Genome Nexus requests across all batches.
+	Retries int `json:"retries"`
+	// CacheHits is the number of genomic locations answered from the annotation cache.
+	CacheHits int `json:"cacheHits,omitempty"`
+	// Enrichments holds the enrichments requested with WithEnrichments for
+	// every event, in the same order as the events; nil for events that were
+	// not annotated.
+	Enrichments []*EventEnrichment `json:"enrichments,omitempty"`
+	// Filters holds the verdict of the filters set with WithFilters, or of
+	// GermlineFilter.Apply, on every event, in the same order as the events;
+	// nil for events that were not annotated or that no filter applied to.
+	Filters []*FilterResult `json:"filters,omitempty"`
+	// DryRun holds the request that would have been sent when the annotator
+	// is in dry-run mode.
+	DryRun *DryRunPreview `json:"dryRun,omitempty"`
+	// Provenance records the Genome Nexus and VEP versions and settings used.
+	Provenance
+}
+
+// BatchTiming records a single batched request to Genome Nexus.
+type BatchTiming struct {
+	Size     int           `json:"size"`
+	Attempts int           `json:"attempts"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func newAnnotationReport(events int) *AnnotationReport {
+	return &AnnotationReport{
+		Statuses:     make([]AnnotationStatus, events),
+		StatusCounts: make(map[AnnotationStatusCode]int),
+	}
+}
+
+// tally fills in the status counts, failed query keys and query key sharing
+// from the per-event statuses.
+func (r *AnnotationReport) tally() {
+	r.StatusCounts = make(map[AnnotationStatusCode]int)
+	r.FailedQueryKeys = nil
+	failed := make(map[string]bool)
+	queried := make(map[string]int)
+	for _, s := range r.Statuses {
+		r.StatusCounts[s.Code]++
+		if s.wasQueried() {
+			queried[s.QueryKey]++
+		}
+		if s.IsSuccess() || s.Code == StatusSkippedCached || s.Code == StatusDryRun || failed[s.QueryKey] {
+			continue
+		}
+		failed[s.QueryKey] = true
+		r.FailedQueryKeys = append(r.FailedQueryKeys, s.QueryKey)
+	}
+	r.UniqueQueryKeys = len(queried)
+	r.SharedKeyEvents = 0
+	for _, n := range queried {
+		if n > 1 {
+			r.SharedKeyEvents += n
+		}
+	}
+}
+
+// Succeeded returns the number of events that were annotated.
+func (r *AnnotationReport) Succeeded() int {
+	return r.StatusCounts[StatusSuccess]
+}
+
+// Failed returns the number of events that were neither annotated, skipped
+// nor held back by a dry run.
+func (r *AnnotationReport) Failed() int {
+	return len(r.Statuses) - r.StatusCounts[StatusSuccess] - r.StatusCounts[StatusSkippedCached] - r.StatusCounts[StatusDryRun]
+}
@@ -22,7 +22,6 @@ const (
 
 var (
 	dbeventRsidRegex      = regexp.MustCompile("^(rs\\d*)$")
-	proteinPositionRegex  = regexp.MustCompile("p.[A-Za-z]([0-9]*).*$")
 	validNucleotidesRegex = regexp.MustCompile("^([ATGC]*)$")
 )
 
@@ -184,15 +183,35 @@ func resolveHgvsc(canonicalTranscript gnapi.TranscriptConsequenceSummary) string
 }
 
 func resolveHgvsp(canonicalTranscript gnapi.TranscriptConsequenceSummary) string {
-	if canonicalTranscript != (gnapi.TranscriptConsequenceSummary{}) && canonicalTranscript.Hgvsp != nil {
-		return *canonicalTranscript.Hgvsp
+	if canonicalTranscript != (gnapi.TranscriptConsequenceSummary{}) {
+		if canonicalTranscript.Hgvsp != nil && *canonicalTranscript.Hgvsp != "" {
+			return *canonicalTranscript.Hgvsp
+		}
+		// fill in from HGVSp_Short when Genome Nexus only returned the one-letter form
+		if canonicalTranscript.HgvspShort != nil && *canonicalTranscript.HgvspShort != "" {
+			if hgvsp, err := hgvspShortToLong(*canonicalTranscript.HgvspShort); err == nil {
+				return hgvsp
+			}
+		}
 	}
 	return ""
 }
 
 func resolveHgvspShort(canonicalTranscript gnapi.TranscriptConsequenceSummary) string {
-	if canonicalTranscript != (gnapi.TranscriptConsequenceSummary{}) && canonicalTranscript.HgvspShort != nil {
-		return *canonicalTranscript.HgvspShort
+	if canonicalTranscript != (gnapi.TranscriptConsequenceSummary{}) {
+		if canonicalTranscript.HgvspShort != nil && *canonicalTranscript.HgvspShort != "" {
+			// write stop codons as *, keeping what cannot be parsed as is
+			if hgvspShort, err := NormalizeHgvspShort(*canonicalTranscript.HgvspShort); err == nil {
+				return hgvspShort
+			}
+			return *canonicalTranscript.HgvspShort
+		}
+		// fill in from HGVSp when Genome Nexus only returned the three-letter form
+		if canonicalTranscript.Hgvsp != nil && *canonicalTranscript.Hgvsp != "" {
+			if hgvspShort, err := hgvspToShort(*canonicalTranscript.Hgvsp); err == nil {
+				return hgvspShort
+			}
+		}
 	}
 	return ""
 }
@@ -248,22 +267,28 @@ func resolveConsequence(canonicalTranscript gnapi.TranscriptConsequenceSummary)
 	return ""
 }
 
-func resolveProteinPosition(canonicalTranscript gnapi.TranscriptConsequenceSummary) string {
+func resolveProteinPosition(canonicalTranscript gnapi.TranscriptConsequenceSummary, event *tempotype.Event, writeRange bool) string {
+	return resolveProteinPositionRange(canonicalTranscript, event).format(writeRange)
+}
+
+// resolveProteinPositionRange tries, in order, the Genome Nexus protein position, HGVSp_Short, HGVSp
+// and finally the ProteinPosition of the incoming event (the equivalent of the mutation record's
+// additional properties in the original method).
+func resolveProteinPositionRange(canonicalTranscript gnapi.TranscriptConsequenceSummary, event *tempotype.Event) proteinPosition {
 	if canonicalTranscript != (gnapi.TranscriptConsequenceSummary{}) {
-		var proteinPosStart string = resolveProteinPosStart(canonicalTranscript)
-		var hgvspShort string = resolveHgvspShort(canonicalTranscript)
-		if proteinPosStart != "" {
-			return proteinPosStart
-		} else if hgvspShort != "" {
-			// try extracting from hgvspShort if proteinPosStart empty
-			var match = proteinPositionRegex.FindStringSubmatch(hgvspShort)
-			if match != nil && match[1] != "" {
-				return match[1]
-			}
+		start, _ := strconv.Atoi(resolveProteinPosStart(canonicalTranscript))
+		end, _ := strconv.Atoi(resolveProteinPosEnd(canonicalTranscript))
+		if start != 0 {
+			return newProteinPosition(start, end)
+		}
+		if pos := parseHgvspPosition(resolveHgvspShort(canonicalTranscript)); !pos.isEmpty() {
+			return pos
+		}
+		if pos := parseHgvspPosition(resolveHgvsp(canonicalTranscript)); !pos.isEmpty() {
+			return pos
 		}
 	}
-	// NOTE: In the original method, it tries to get proteinPosition from the mutationRecord's additionalProperties
-	return ""
+	return parseVepProteinPosition(event.ProteinPosition)
 }
 
 func resolveExon(canonicalTranscript gnapi.TranscriptConsequenceSummary) string {
@@ -389,22 +414,7 @@ func getCanonicalRawTranscript(va gnapi.VariantAnnotation) *gnapi.TranscriptCons
 
 // getGnomadExomeAF returns the gnomAD exome AlleleFrequency or nil if unavailable.
 func getGnomadExomeAF(va gnapi.VariantAnnotation) *gnapi.AlleleFrequency {
-	if va.MyVariantInfo == nil {
-		return nil
-	}
-	mviAnnot, ok := va.MyVariantInfo.GetAnnotationOk()
-	if !ok || mviAnnot == nil {
-		return nil
-	}
-	gnomad, ok := mviAnnot.GetGnomadExomeOk()
-	if !ok || gnomad == nil {
-		return nil
-	}
-	af, ok := gnomad.GetAlleleFrequencyOk()
-	if !ok || af == nil {
-		return nil
-	}
-	return af
+	return getGnomadAF(va, GnomadSourceExome)
 }
 
 // getMutationAssessor returns the MutationAssessor annotation or nil if unavailable.
@@ -443,78 +453,51 @@ func domainsToString(domains []map[string]string) string {
 	return strings.Join(parts, ";")
 }
 
-// gnomAD resolvers (from MyVariantInfo.GnomadExome.AlleleFrequency)
+// gnomAD resolvers (from MyVariantInfo.GnomadExome.AlleleFrequency, or the
+// first source of preference with data when one is given)
 
-func resolveGnomadAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
+func formatGnomadValue(va gnapi.VariantAnnotation, value func(*gnapi.AlleleFrequency) float64, preference []GnomadSource) string {
+	af, _, ok := resolveGnomadValue(va, value, preference)
+	if !ok {
 		return ""
 	}
-	return formatFloat(af.Af)
+	return formatFloat(af)
 }
 
-func resolveGnomadAfrAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfAfr)
+func resolveGnomadAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.Af }, preference)
 }
 
-func resolveGnomadAmrAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfAmr)
+func resolveGnomadAfrAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfAfr }, preference)
 }
 
-func resolveGnomadAsjAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfAsj)
+func resolveGnomadAmrAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfAmr }, preference)
 }
 
-func resolveGnomadEasAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfEas)
+func resolveGnomadAsjAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfAsj }, preference)
 }
 
-func resolveGnomadFinAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfFin)
+func resolveGnomadEasAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfEas }, preference)
 }
 
-func resolveGnomadNfeAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfNfe)
+func resolveGnomadFinAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfFin }, preference)
 }
 
-func resolveGnomadOthAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfOth)
+func resolveGnomadNfeAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfNfe }, preference)
 }
 
-func resolveGnomadSasAF(va gnapi.VariantAnnotation) string {
-	af := getGnomadExomeAF(va)
-	if af == nil {
-		return ""
-	}
-	return formatFloat(af.AfSas)
+func resolveGnomadOthAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfOth }, preference)
+}
+
+func resolveGnomadSasAF(va gnapi.VariantAnnotation, preference ...GnomadSource) string {
+	return formatGnomadValue(va, func(af *gnapi.AlleleFrequency) float64 { return af.AfSas }, preference)
 }
 
 // Mutation Assessor resolvers
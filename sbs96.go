@@ -0,0 +1,112 @@
+package genome_nexus_annotator_go
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// SBS96Channels lists the 96 single base substitution channels in the order
+// of the COSMIC mutational signatures: by substitution, then 5' base, then 3'
+// base.
+var SBS96Channels = sbs96Channels()
+
+func sbs96Channels() []string {
+	channels := make([]string, 0, 96)
+	for _, substitution := range []string{"C>A", "C>G", "C>T", "T>A", "T>C", "T>G"} {
+		for _, five := range "ACGT" {
+			for _, three := range "ACGT" {
+				channels = append(channels, string(five)+"["+substitution+"]"+string(three))
+			}
+		}
+	}
+	return channels
+}
+
+// SBS96Matrix counts the SNVs of each sample in every SBS96 channel.
+type SBS96Matrix struct {
+	// Samples lists the samples in the order they were first seen.
+	Samples []string
+	// Counts holds the counts of each sample, indexed like SBS96Channels.
+	Counts map[string][]int
+}
+
+func NewSBS96Matrix() *SBS96Matrix {
+	return &SBS96Matrix{Counts: make(map[string][]int)}
+}
+
+// BuildSBS96Matrix counts the annotated SNVs of tms by sample. reports holds
+// the report of each message, in the same order, as returned in
+// MessagesReport.Reports; the messages must have been annotated with
+// EnrichmentNucleotideContext. Messages of the same sample are added up.
+func BuildSBS96Matrix(tms []*tt.TempoMessage, reports []*AnnotationReport) (*SBS96Matrix, error) {
+	if len(tms) != len(reports) {
+		return nil, fmt.Errorf("got %d reports for %d messages", len(reports), len(tms))
+	}
+	m := NewSBS96Matrix()
+	for i, tm := range tms {
+		m.addSample(tm.CmoSampleId)
+		report := reports[i]
+		if report == nil || report.Enrichments == nil {
+			continue
+		}
+		for j, e := range report.Enrichments {
+			if j >= len(report.Statuses) || !report.Statuses[j].IsSuccess() {
+				continue
+			}
+			if e != nil && e.NucleotideContext != nil {
+				m.Add(tm.CmoSampleId, e.NucleotideContext.SBS96)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *SBS96Matrix) addSample(sample string) []int {
+	counts, ok := m.Counts[sample]
+	if !ok {
+		counts = make([]int, len(SBS96Channels))
+		m.Counts[sample] = counts
+		m.Samples = append(m.Samples, sample)
+	}
+	return counts
+}
+
+// Add counts an SNV of sample in channel. It returns false when channel is not
+// an SBS96 channel.
+func (m *SBS96Matrix) Add(sample, channel string) bool {
+	i := slices.Index(SBS96Channels, channel)
+	if i < 0 {
+		return false
+	}
+	m.addSample(sample)[i]++
+	return true
+}
+
+// WriteTSV writes the matrix with a row per channel and a column per sample,
+// headed by MutationType, as read by SigProfiler and most signature fitting
+// tools.
+func (m *SBS96Matrix) WriteTSV(w io.Writer) error {
+	header := append([]string{"MutationType"}, m.Samples...)
+	for i := range header {
+		header[i] = tsvValue(header[i])
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+	for i, channel := range SBS96Channels {
+		row := make([]string, 0, len(m.Samples)+1)
+		row = append(row, channel)
+		for _, sample := range m.Samples {
+			row = append(row, strconv.Itoa(m.Counts[sample][i]))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
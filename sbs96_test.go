@@ -0,0 +1,64 @@
+package genome_nexus_annotator_go
+
+import (
+	"strings"
+	"testing"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func TestSBS96Channel(t *testing.T) {
+	tests := []struct {
+		context, alt, expected string
+	}{
+		{"ACC", "T", "A[C>T]C"},
+		{"TCG", "A", "T[C>A]G"},
+		{"CAC", "T", "G[T>A]G"},
+		{"AGT", "A", "A[C>T]T"},
+		{"ACC", "C", ""},
+		{"ANC", "T", ""},
+	}
+	for _, tc := range tests {
+		if got := sbs96Channel(tc.context, tc.alt); got != tc.expected {
+			t.Errorf("sbs96Channel(%q, %q) = %q, expected %q", tc.context, tc.alt, got, tc.expected)
+		}
+	}
+	if len(SBS96Channels) != 96 || SBS96Channels[0] != "A[C>A]A" || SBS96Channels[95] != "T[T>G]T" {
+		t.Errorf("unexpected SBS96 channels %v", SBS96Channels)
+	}
+}
+
+func TestBuildSBS96Matrix(t *testing.T) {
+	success := AnnotationStatus{Code: StatusSuccess}
+	sbs96 := func(channel string) *EventEnrichment {
+		return &EventEnrichment{NucleotideContext: &NucleotideContextEnrichment{SBS96: channel}}
+	}
+	tms := []*tt.TempoMessage{{CmoSampleId: "s1"}, {CmoSampleId: "s2"}, {CmoSampleId: "s1"}}
+	reports := []*AnnotationReport{
+		{Statuses: []AnnotationStatus{success, success, success}, Enrichments: []*EventEnrichment{sbs96("A[C>A]A"), sbs96("A[C>A]A"), nil}},
+		{Statuses: []AnnotationStatus{success, {Code: StatusGNUnsuccessful}}, Enrichments: []*EventEnrichment{sbs96("T[T>G]T"), sbs96("A[C>A]A")}},
+		{Statuses: []AnnotationStatus{success}, Enrichments: []*EventEnrichment{sbs96("T[T>G]T")}},
+	}
+	m, err := BuildSBS96Matrix(tms, reports)
+	if err != nil {
+		t.Fatalf("BuildSBS96Matrix failed: %v", err)
+	}
+
+	var b strings.Builder
+	if err := m.WriteTSV(&b); err != nil {
+		t.Fatalf("WriteTSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 97 {
+		t.Fatalf("expected a header and 96 rows but got %d lines", len(lines))
+	}
+	for i, expected := range map[int]string{0: "MutationType\ts1\ts2", 1: "A[C>A]A\t2\t0", 2: "A[C>A]C\t0\t0", 96: "T[T>G]T\t1\t1"} {
+		if lines[i] != expected {
+			t.Errorf("line %d: expected %q but got %q", i, expected, lines[i])
+		}
+	}
+
+	if _, err := BuildSBS96Matrix(tms, reports[:1]); err == nil {
+		t.Error("expected an error when the reports do not match the messages")
+	}
+}
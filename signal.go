@@ -0,0 +1,106 @@
+package genome_nexus_annotator_go
+
+import (
+	"strings"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+)
+
+// SignalEnrichment holds the SIGNAL frequencies of a variant in cancer
+// patients, one record per mutation status.
+type SignalEnrichment struct {
+	Records []SignalRecord `json:"records"`
+}
+
+// SignalRecord is the SIGNAL data of a variant for one mutation status.
+type SignalRecord struct {
+	// MutationStatus is "germline" or "somatic".
+	MutationStatus string `json:"mutationStatus"`
+	// GermlineFrequency is the frequency of the variant in cancer patients.
+	GermlineFrequency *float64 `json:"germlineFrequency,omitempty"`
+	// PathogenicGermlineRatio is the fraction of carriers with a pathogenic
+	// germline variant.
+	PathogenicGermlineRatio *float64 `json:"pathogenicGermlineRatio,omitempty"`
+	// BiallelicRatio is the fraction of carriers with a biallelic inactivation.
+	BiallelicRatio          *float64               `json:"biallelicRatio,omitempty"`
+	GermlineHomozygousCount int                    `json:"germlineHomozygousCount"`
+	TumorTypes              []SignalTumorTypeCount `json:"tumorTypes,omitempty"`
+}
+
+// SignalTumorTypeCount is the number of carriers of a variant among the
+// patients with a tumor type.
+type SignalTumorTypeCount struct {
+	TumorType      string `json:"tumorType"`
+	TumorTypeCount int    `json:"tumorTypeCount"`
+	VariantCount   int    `json:"variantCount"`
+	BiallelicCount int    `json:"biallelicCount"`
+}
+
+// Frequency returns the fraction of patients with the tumor type carrying the
+// variant.
+func (c SignalTumorTypeCount) Frequency() float64 {
+	if c.TumorTypeCount == 0 {
+		return 0
+	}
+	return float64(c.VariantCount) / float64(c.TumorTypeCount)
+}
+
+// Germline returns the germline record, or nil when SIGNAL has none.
+func (s *SignalEnrichment) Germline() *SignalRecord {
+	if s == nil {
+		return nil
+	}
+	for i := range s.Records {
+		if strings.EqualFold(s.Records[i].MutationStatus, "germline") {
+			return &s.Records[i]
+		}
+	}
+	return nil
+}
+
+// resolveSignal returns the SIGNAL records of the variant, or nil when the
+// variant is not in SIGNAL.
+func resolveSignal(gnResponse gnapi.VariantAnnotation) *SignalEnrichment {
+	if gnResponse.SignalAnnotation == nil || len(gnResponse.SignalAnnotation.Annotation) == 0 {
+		return nil
+	}
+	enrichment := &SignalEnrichment{}
+	for _, m := range gnResponse.SignalAnnotation.Annotation {
+		record := SignalRecord{
+			GermlineFrequency:       m.GermlineFrequency,
+			PathogenicGermlineRatio: m.PathogenicGermlineRatio,
+			BiallelicRatio:          m.BiallelicGermlineRatio,
+		}
+		if m.MutationStatus != nil {
+			record.MutationStatus = strings.ToLower(*m.MutationStatus)
+		}
+		if m.OverallNumberOfGermlineHomozygous != nil {
+			record.GermlineHomozygousCount = int(*m.OverallNumberOfGermlineHomozygous)
+		}
+		byTumorType := make(map[string]int)
+		for _, c := range m.CountsByTumorType {
+			count := SignalTumorTypeCount{}
+			if c.TumorType != nil {
+				count.TumorType = *c.TumorType
+			}
+			if c.TumorTypeCount != nil {
+				count.TumorTypeCount = int(*c.TumorTypeCount)
+			}
+			if c.VariantCount != nil {
+				count.VariantCount = int(*c.VariantCount)
+			}
+			byTumorType[count.TumorType] = len(record.TumorTypes)
+			record.TumorTypes = append(record.TumorTypes, count)
+		}
+		for _, c := range m.BiallelicCountsByTumorType {
+			if c.TumorType == nil || c.VariantCount == nil {
+				continue
+			}
+			if i, ok := byTumorType[*c.TumorType]; ok {
+				record.TumorTypes[i].BiallelicCount = int(*c.VariantCount)
+			}
+		}
+		enrichment.Records = append(enrichment.Records, record)
+	}
+	return enrichment
+}
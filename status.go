@@ -0,0 +1,61 @@
+package genome_nexus_annotator_go
+
+import (
+	"fmt"
+)
+
+// AnnotationStatusCode classifies the outcome of annotating a single event.
+type AnnotationStatusCode string
+
+const (
+	// StatusSuccess means the event was annotated by Genome Nexus.
+	StatusSuccess AnnotationStatusCode = "SUCCESS"
+	// StatusNoResponse means Genome Nexus returned no annotation for the event's genomic location.
+	StatusNoResponse AnnotationStatusCode = "NO_RESPONSE"
+	// StatusGNUnsuccessful means Genome Nexus returned an annotation flagged as unsuccessful.
+	StatusGNUnsuccessful AnnotationStatusCode = "GN_UNSUCCESSFUL"
+	// StatusInvalidInput means the event could not be turned into a valid genomic location.
+	StatusInvalidInput AnnotationStatusCode = "INVALID_INPUT"
+	// StatusReferenceMismatch means the reference allele did not match the reference genome.
+	StatusReferenceMismatch AnnotationStatusCode = "REFERENCE_MISMATCH"
+	// StatusTransportError means the request to Genome Nexus failed.
+	StatusTransportError AnnotationStatusCode = "TRANSPORT_ERROR"
+	// StatusSkippedCached means the event was not sent because an earlier annotation was kept.
+	StatusSkippedCached AnnotationStatusCode = "SKIPPED_CACHED"
+	// StatusDryRun means the event would have been sent but the annotator is in dry-run mode.
+	StatusDryRun AnnotationStatusCode = "DRY_RUN"
+)
+
+// AnnotationStatus is the structured outcome of annotating a single event.
+type AnnotationStatus struct {
+	Code         AnnotationStatusCode `json:"code"`
+	Reason       string               `json:"reason,omitempty"`
+	QueryKey     string               `json:"queryKey,omitempty"`
+	ErrorMessage string               `json:"errorMessage,omitempty"`
+}
+
+// String returns the concise form written to Event.AnnotationStatus, either
+// "SUCCESS" or "FAILURE: <CODE>: <query key>".
+func (s AnnotationStatus) String() string {
+	if s.Code == StatusSuccess {
+		return string(StatusSuccess)
+	}
+	if s.QueryKey == "" {
+		return fmt.Sprintf("FAILURE: %s", s.Code)
+	}
+	return fmt.Sprintf("FAILURE: %s: %s", s.Code, s.QueryKey)
+}
+
+// IsSuccess reports whether the event was annotated.
+func (s AnnotationStatus) IsSuccess() bool {
+	return s.Code == StatusSuccess
+}
+
+// wasQueried reports whether the event's genomic location was sent to Genome Nexus.
+func (s AnnotationStatus) wasQueried() bool {
+	switch s.Code {
+	case StatusInvalidInput, StatusReferenceMismatch, StatusSkippedCached:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package genome_nexus_annotator_go
+
+import (
+	"context"
+	"time"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+const (
+	defaultStreamWindow time.Duration = time.Second
+)
+
+// StreamResult is a TempoMessage emitted by AnnotateStream once all of its events are resolved.
+type StreamResult struct {
+	Message *tt.TempoMessage
+	Report  *AnnotationReport
+	// Err holds the Genome Nexus request errors that affected this message's events.
+	Err error
+}
+
+// AnnotateStream annotates the TempoMessages read from in and emits each of
+// them, in order, once all of its events are resolved. Messages are
+// micro-batched until the window holds at least the configured number of
+// events or the window time elapses, so locations shared between messages in
+// the same window are only sent once. No more messages are read while results
+// are waiting to be received. The returned channel is closed after in is closed
+// and drained, or when ctx is done.
+func (gn GNAnnotatorService) AnnotateStream(
+	ctx context.Context,
+	isoformOverrideSource string,
+	in <-chan *tt.TempoMessage,
+) <-chan StreamResult {
+	out := make(chan StreamResult)
+	// requests made for the stream are bound to its context
+	sgn := gn
+	sgn.ctxAccessToken = ctx
+
+	go func() {
+		defer close(out)
+		pending := make([]*tt.TempoMessage, 0)
+		pendingEvents := 0
+		var timer *time.Timer
+		var timeout <-chan time.Time
+
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(pending) == 0 {
+				return true
+			}
+			batch := pending
+			pending, pendingEvents = make([]*tt.TempoMessage, 0), 0
+			mr, _ := sgn.AnnotateTempoMessages(isoformOverrideSource, batch)
+			for i, tm := range batch {
+				select {
+				case out <- StreamResult{Message: tm, Report: mr.Reports[i], Err: mr.Errors[i]}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case tm, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if tm == nil {
+					continue
+				}
+				pending = append(pending, tm)
+				pendingEvents += len(tm.Events)
+				if timer == nil {
+					timer = time.NewTimer(gn.streamWindow)
+					timeout = timer.C
+				}
+				if pendingEvents >= gn.streamMaxEvents && !flush() {
+					return
+				}
+			case <-timeout:
+				timer, timeout = nil, nil
+				if !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,101 @@
+package genome_nexus_annotator_go
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func TestAnnotateStream(t *testing.T) {
+	var mu sync.Mutex
+	queried := make(map[string]int)
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		queried[buildGenomicLocationKey(gl)]++
+		return fakeAnnotation(gl, "TP53"), gl.Chromosome != "3"
+	})
+	requests := 0
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/annotation/genomic" {
+			mu.Lock()
+			requests++
+			mu.Unlock()
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+	// the window never expires during the test, batches are only flushed by
+	// size and when the input is closed
+	gn, err := NewGNAnnotatorService(context.Background(), "", counting.URL, WithStreamWindow(3, time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+
+	samples := []string{"S1", "S2", "S3"}
+	in := make(chan *tt.TempoMessage, len(samples))
+	in <- &tt.TempoMessage{CmoSampleId: "S1", Events: []*tt.Event{
+		newTestEvent("17", "7577120", "7577120", "C", "T"),
+		newTestEvent("3", "100", "100", "A", "G"),
+	}}
+	in <- &tt.TempoMessage{CmoSampleId: "S2", Events: []*tt.Event{
+		newTestEvent("17", "7577120", "7577120", "C", "T"),
+	}}
+	// flushed on its own once the input is closed
+	in <- &tt.TempoMessage{CmoSampleId: "S3", Events: []*tt.Event{
+		newTestEvent("17", "7578406", "7578406", "C", "T"),
+	}}
+	close(in)
+	out := gn.AnnotateStream(context.Background(), isoformOverrideString, in)
+
+	i := 0
+	for result := range out {
+		if result.Message.CmoSampleId != samples[i] {
+			t.Errorf("expected message %q but got %q", samples[i], result.Message.CmoSampleId)
+		}
+		if result.Err != nil {
+			t.Errorf("message %q: unexpected error %v", result.Message.CmoSampleId, result.Err)
+		}
+		if result.Report.Succeeded() != 1 {
+			t.Errorf("message %q: expected 1 annotated event but got %d", result.Message.CmoSampleId, result.Report.Succeeded())
+		}
+		i++
+	}
+	if i != len(samples) {
+		t.Fatalf("expected %d results but got %d", len(samples), i)
+	}
+	if requests != 2 {
+		t.Errorf("expected a request for S1 and S2 and one for S3 but got %d requests", requests)
+	}
+	if queried["17,7577120,7577120,C,T"] != 1 {
+		t.Errorf("expected the shared location to be sent once but it was sent %d times", queried["17,7577120,7577120,C,T"])
+	}
+}
+
+func TestAnnotateStreamCancel(t *testing.T) {
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "TP53"), true
+	})
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan *tt.TempoMessage)
+	out := gn.AnnotateStream(ctx, isoformOverrideString, in)
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Errorf("expected no results after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("stream was not closed after cancellation")
+	}
+}
@@ -0,0 +1,236 @@
+package genome_nexus_annotator_go
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+// NonSynonymousClassifications lists the variant classifications counted by
+// default in the tumor mutational burden.
+var NonSynonymousClassifications = []string{
+	"Missense_Mutation",
+	"Nonsense_Mutation",
+	"Nonstop_Mutation",
+	"Frame_Shift_Del",
+	"Frame_Shift_Ins",
+	"In_Frame_Del",
+	"In_Frame_Ins",
+	"Splice_Site",
+	"Translation_Start_Site",
+}
+
+// TargetRegions are the target regions of a sequencing panel, merged so that
+// overlapping or duplicated regions are counted once.
+type TargetRegions struct {
+	// regions holds the sorted, non-overlapping 0-based half-open regions of
+	// every chromosome.
+	regions map[string][][2]int64
+}
+
+// LoadBED reads the target regions of the BED file at path.
+func LoadBED(path string) (*TargetRegions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open BED file %q: %v", path, err)
+	}
+	defer f.Close()
+	regions, err := ReadBED(f)
+	if err != nil {
+		return nil, fmt.Errorf("BED file %q: %v", path, err)
+	}
+	return regions, nil
+}
+
+// ReadBED reads target regions in BED format. Only the chrom, chromStart and
+// chromEnd columns are used; header, track and browser lines are skipped.
+func ReadBED(r io.Reader) (*TargetRegions, error) {
+	byChromosome := make(map[string][][2]int64)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "track") || strings.HasPrefix(text, "browser") {
+			continue
+		}
+		cols := strings.Fields(text)
+		if len(cols) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 columns but got %d", line, len(cols))
+		}
+		start, err := strconv.ParseInt(cols[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		end, err := strconv.ParseInt(cols[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("line %d: invalid region %s:%d-%d", line, cols[0], start, end)
+		}
+		chromosome := normalizeChromosome(cols[0])
+		byChromosome[chromosome] = append(byChromosome[chromosome], [2]int64{start, end})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read BED: %v", err)
+	}
+
+	t := &TargetRegions{regions: make(map[string][][2]int64, len(byChromosome))}
+	for chromosome, regions := range byChromosome {
+		slices.SortFunc(regions, func(a, b [2]int64) int {
+			return cmp.Compare(a[0], b[0])
+		})
+		merged := regions[:0]
+		for _, r := range regions {
+			if n := len(merged); n > 0 && r[0] <= merged[n-1][1] {
+				merged[n-1][1] = max(merged[n-1][1], r[1])
+				continue
+			}
+			merged = append(merged, r)
+		}
+		t.regions[chromosome] = merged
+	}
+	return t, nil
+}
+
+// Size returns the number of bases covered by the target regions.
+func (t *TargetRegions) Size() int64 {
+	var size int64
+	for _, regions := range t.regions {
+		for _, r := range regions {
+			size += r[1] - r[0]
+		}
+	}
+	return size
+}
+
+// Contains returns whether the 1-based position of chromosome is in a target
+// region.
+func (t *TargetRegions) Contains(chromosome string, position int64) bool {
+	regions := t.regions[normalizeChromosome(chromosome)]
+	i, _ := slices.BinarySearchFunc(regions, position, func(r [2]int64, pos int64) int {
+		if r[1] < pos {
+			return -1
+		}
+		return 1
+	})
+	return i < len(regions) && regions[i][0] < position
+}
+
+// TMBCalculator computes the tumor mutational burden of annotated messages.
+type TMBCalculator struct {
+	// PanelSize is the number of bases sequenced, usually the size of the
+	// panel target regions.
+	PanelSize int64
+	// Regions, if set, only counts the events starting in a target region.
+	Regions *TargetRegions
+	// Classifications lists the variant classifications counted, by default
+	// NonSynonymousClassifications.
+	Classifications []string
+}
+
+// NewTMBCalculator returns a calculator counting the events in the target
+// regions of the BED file at path, over the size of those regions.
+func NewTMBCalculator(path string) (*TMBCalculator, error) {
+	regions, err := LoadBED(path)
+	if err != nil {
+		return nil, err
+	}
+	return &TMBCalculator{PanelSize: regions.Size(), Regions: regions}, nil
+}
+
+// TMBResult is the tumor mutational burden of a message.
+type TMBResult struct {
+	SampleId string `json:"sampleId"`
+	// TMB is the number of counted events per megabase.
+	TMB       float64 `json:"tmb"`
+	PanelSize int64   `json:"panelSize"`
+	// Events lists the index in the message of every counted event.
+	Events []int `json:"events"`
+	// Duplicates and Filtered are the numbers of eligible events left out as
+	// duplicates of a counted event, or because a filter flagged them as
+	// germline variants or artifacts.
+	Duplicates int `json:"duplicates"`
+	Filtered   int `json:"filtered"`
+}
+
+// Count returns the number of counted events.
+func (r *TMBResult) Count() int {
+	return len(r.Events)
+}
+
+// ComputeTMB computes the tumor mutational burden of every message of tms.
+// reports holds the report of each message, in the same order, as returned in
+// MessagesReport.Reports.
+func (c *TMBCalculator) ComputeTMB(tms []*tt.TempoMessage, reports []*AnnotationReport) ([]*TMBResult, error) {
+	if len(tms) != len(reports) {
+		return nil, fmt.Errorf("got %d reports for %d messages", len(reports), len(tms))
+	}
+	results := make([]*TMBResult, len(tms))
+	for i, tm := range tms {
+		result, err := c.Compute(tm, reports[i])
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// Compute computes the tumor mutational burden of tm, whose annotation report
+// is report. An event is eligible if it was annotated, or kept from an earlier
+// annotation when reannotating, and has a counted variant classification.
+// Eligible events flagged by a filter in report.Filters or with a germline
+// Mutation_Status are left out, and events at the same position with the same
+// alleles are counted once.
+func (c *TMBCalculator) Compute(tm *tt.TempoMessage, report *AnnotationReport) (*TMBResult, error) {
+	if c.PanelSize <= 0 {
+		return nil, fmt.Errorf("invalid panel size %d", c.PanelSize)
+	}
+	if report == nil {
+		return nil, fmt.Errorf("missing annotation report for sample %q", tm.CmoSampleId)
+	}
+	classifications := c.Classifications
+	if classifications == nil {
+		classifications = NonSynonymousClassifications
+	}
+	result := &TMBResult{SampleId: tm.CmoSampleId, PanelSize: c.PanelSize, Events: []int{}}
+	seen := make(map[string]bool)
+	for i, e := range tm.Events {
+		if i >= len(report.Statuses) {
+			continue
+		}
+		if status := report.Statuses[i]; !status.IsSuccess() && status.Code != StatusSkippedCached {
+			continue
+		}
+		if !slices.Contains(classifications, e.VariantClassification) {
+			continue
+		}
+		if c.Regions != nil {
+			start, err := strconv.ParseInt(e.StartPosition, 10, 64)
+			if err != nil || !c.Regions.Contains(e.Chromosome, start) {
+				continue
+			}
+		}
+		if (i < len(report.Filters) && report.Filters[i] != nil && report.Filters[i].Verdict != FilterSomatic) ||
+			strings.EqualFold(e.MutationStatus, GermlineMutationStatus) {
+			result.Filtered++
+			continue
+		}
+		key := strings.Join([]string{normalizeChromosome(e.Chromosome), e.StartPosition, e.EndPosition, e.ReferenceAllele, e.TumorSeqAllele2}, ":")
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		seen[key] = true
+		result.Events = append(result.Events, i)
+	}
+	result.TMB = float64(len(result.Events)) / (float64(c.PanelSize) / 1e6)
+	return result, nil
+}
@@ -0,0 +1,145 @@
+package genome_nexus_annotator_go
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	gnapi "github.com/genome-nexus/genome-nexus-go-api-client/genome-nexus-public-api"
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func TestReadBED(t *testing.T) {
+	bed := "track name=panel\n# comment\nchr1\t100\t200\tgeneA\n1\t150\t250\n1\t250\t300\nchr7\t140453100\t140453200\nchrM\t0\t10\n"
+	regions, err := ReadBED(strings.NewReader(bed))
+	if err != nil {
+		t.Fatalf("ReadBED failed: %v", err)
+	}
+	if size := regions.Size(); size != 200+100+10 {
+		t.Errorf("expected a size of 310 but got %d", size)
+	}
+	for _, tc := range []struct {
+		chromosome string
+		position   int64
+		expected   bool
+	}{
+		{"1", 100, false},
+		{"1", 101, true},
+		{"chr1", 300, true},
+		{"1", 301, false},
+		{"7", 140453136, true},
+		{"MT", 10, true},
+		{"2", 150, false},
+	} {
+		if got := regions.Contains(tc.chromosome, tc.position); got != tc.expected {
+			t.Errorf("Contains(%q, %d) = %v, expected %v", tc.chromosome, tc.position, got, tc.expected)
+		}
+	}
+
+	for _, invalid := range []string{"1\t100\n", "1\tx\t200\n", "1\t200\t100\n"} {
+		if _, err := ReadBED(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected an error reading %q", invalid)
+		}
+	}
+}
+
+func TestComputeTMB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "panel.bed")
+	if err := os.WriteFile(path, []byte("7\t0\t1000000\n12\t0\t1000000\n"), 0o644); err != nil {
+		t.Fatalf("failed to write BED: %v", err)
+	}
+	c, err := NewTMBCalculator(path)
+	if err != nil {
+		t.Fatalf("NewTMBCalculator failed: %v", err)
+	}
+
+	classified := func(e *tt.Event, classification string) *tt.Event {
+		e.VariantClassification = classification
+		return e
+	}
+	germline := classified(newTestEvent("12", "200", "200", "C", "T"), "Missense_Mutation")
+	germline.MutationStatus = GermlineMutationStatus
+	tm := &tt.TempoMessage{CmoSampleId: "s1", Events: []*tt.Event{
+		classified(newTestEvent("7", "100", "100", "A", "T"), "Missense_Mutation"),
+		classified(newTestEvent("chr7", "100", "100", "A", "T"), "Missense_Mutation"),
+		classified(newTestEvent("7", "300", "300", "G", "-"), "Frame_Shift_Del"),
+		classified(newTestEvent("7", "400", "400", "C", "T"), "Silent"),
+		classified(newTestEvent("12", "100", "100", "C", "A"), "Nonsense_Mutation"),
+		germline,
+		classified(newTestEvent("12", "300", "300", "C", "A"), "Missense_Mutation"),
+		classified(newTestEvent("12", "2000000", "2000000", "C", "A"), "Missense_Mutation"),
+		classified(newTestEvent("7", "500", "500", "C", "A"), "Missense_Mutation"),
+	}}
+	success := AnnotationStatus{Code: StatusSuccess}
+	report := &AnnotationReport{
+		Statuses: []AnnotationStatus{success, success, success, success, {Code: StatusSkippedCached}, success, success, success, {Code: StatusGNUnsuccessful}},
+		Filters:  []*FilterResult{nil, nil, {Verdict: FilterSomatic}, nil, nil, nil, {Verdict: FilterArtifact}, nil, nil},
+	}
+
+	results, err := c.ComputeTMB([]*tt.TempoMessage{tm}, []*AnnotationReport{report})
+	if err != nil {
+		t.Fatalf("ComputeTMB failed: %v", err)
+	}
+	r := results[0]
+	if !slices.Equal(r.Events, []int{0, 2, 4}) || r.Duplicates != 1 || r.Filtered != 2 {
+		t.Errorf("expected events [0 2 4], 1 duplicate and 2 filtered but got %+v", r)
+	}
+	if r.SampleId != "s1" || r.PanelSize != 2000000 || r.TMB != 1.5 {
+		t.Errorf("expected a TMB of 1.5 over 2Mb but got %+v", r)
+	}
+
+	if _, err := (&TMBCalculator{}).Compute(tm, report); err == nil {
+		t.Errorf("expected an error without a panel size")
+	}
+	if _, err := c.ComputeTMB([]*tt.TempoMessage{tm}, nil); err == nil {
+		t.Errorf("expected an error for missing reports")
+	}
+	if _, err := c.Compute(tm, nil); err == nil {
+		t.Errorf("expected an error without a report")
+	}
+}
+
+func TestComputeTMBAfterReannotation(t *testing.T) {
+	server := newFakeGNServer(t, func(gl gnapi.GenomicLocation) (map[string]interface{}, bool) {
+		return fakeAnnotation(gl, "PIK3CA"), true
+	})
+	gn, err := NewGNAnnotatorService(context.Background(), "", server.URL,
+		WithFilters(&ReadCountFilter{MinDepth: 10}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create a GNAnnotatorService: %v", err)
+	}
+	annotated := func(e *tt.Event, tRef, tAlt string) *tt.Event {
+		e.AnnotationStatus = string(StatusSuccess)
+		e.VariantClassification = "Missense_Mutation"
+		e.TRefCount, e.TAltCount = tRef, tAlt
+		return e
+	}
+	tm := &tt.TempoMessage{CmoSampleId: "s1", Events: []*tt.Event{
+		annotated(newTestEvent("3", "100", "100", "G", "A"), "60", "40"),
+		annotated(newTestEvent("3", "200", "200", "G", "A"), "2", "1"),
+		newTestEvent("3", "300", "300", "A", "G"),
+	}}
+	current, err := gn.CurrentAnnotationVersion()
+	if err != nil {
+		t.Fatalf("CurrentAnnotationVersion failed: %v", err)
+	}
+	report, err := gn.ReannotateTempoMessageEvents(isoformOverrideString, tm, current)
+	if err != nil {
+		t.Fatalf("ReannotateTempoMessageEvents failed: %v", err)
+	}
+	if report.Statuses[1].Code != StatusSkippedCached || report.Filters[1] == nil || report.Filters[1].Verdict != FilterArtifact {
+		t.Fatalf("expected the skipped low-depth event to be filtered but got %+v", report.Filters[1])
+	}
+
+	result, err := (&TMBCalculator{PanelSize: 1000000}).Compute(tm, report)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	if !slices.Equal(result.Events, []int{0, 2}) || result.Filtered != 1 {
+		t.Errorf("expected events [0 2] and 1 filtered but got %+v", result)
+	}
+}
@@ -0,0 +1,94 @@
+package genome_nexus_annotator_go
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+/*
+Validation of events before they are turned into genomic locations and sent to
+Genome Nexus. Invalid events are marked INVALID_INPUT and never sent.
+*/
+
+var (
+	// chromosomeRegex only rejects malformed names, leaving numbered sex
+	// chromosomes (23, 24) and unplaced contigs (GL000220.1, chrUn_...) to
+	// Genome Nexus
+	chromosomeRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.\-]*$`)
+	alleleRegex     = regexp.MustCompile(`^(?i)([ACGTN]+|-)$`)
+)
+
+// validateEvent returns an error describing the first problem that would make
+// the genomic location of e meaningless to Genome Nexus.
+func validateEvent(e *tt.Event) error {
+	if e.Chromosome == "" {
+		return fmt.Errorf("chromosome is empty")
+	}
+	if !chromosomeRegex.MatchString(e.Chromosome) {
+		return fmt.Errorf("chromosome %q is not a valid chromosome name", e.Chromosome)
+	}
+	start, err := parsePosition("start position", e.StartPosition)
+	if err != nil {
+		return err
+	}
+	end, err := parsePosition("end position", e.EndPosition)
+	if err != nil {
+		return err
+	}
+	if start > end {
+		return fmt.Errorf("start position %d is after end position %d", start, end)
+	}
+
+	referenceAllele := e.ReferenceAllele
+	variantAllele := resolveTumorSeqAlleleFromInput(e.ReferenceAllele, e.TumorSeqAllele1, e.TumorSeqAllele2)
+	if !alleleRegex.MatchString(referenceAllele) {
+		return fmt.Errorf("reference allele %q must be - or only contain ACGTN", referenceAllele)
+	}
+	if !alleleRegex.MatchString(variantAllele) {
+		return fmt.Errorf("tumor seq allele %q must be - or only contain ACGTN", variantAllele)
+	}
+	if referenceAllele == "-" && variantAllele == "-" {
+		return fmt.Errorf("reference and tumor seq alleles are both -")
+	}
+
+	// positions follow the MAF convention: insertions are placed between the
+	// two flanking bases, everything else spans the reference allele
+	length := end - start + 1
+	switch {
+	case referenceAllele == "-":
+		if length != 2 {
+			return fmt.Errorf("insertion must span its two flanking bases, got %d-%d", start, end)
+		}
+	case int64(len(referenceAllele)) != length:
+		return fmt.Errorf("reference allele %q does not match the length of %d-%d", referenceAllele, start, end)
+	}
+	return nil
+}
+
+// parsePosition parses a 1-based genomic position that must fit in an int32.
+func parsePosition(name, position string) (int64, error) {
+	p, err := strconv.ParseInt(strings.TrimSpace(position), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a valid integer position", name, position)
+	}
+	if p < 1 {
+		return 0, fmt.Errorf("%s %d must be positive", name, p)
+	}
+	return p, nil
+}
+
+// buildEventKey builds a query key from the raw event fields, for events that
+// cannot be turned into a genomic location.
+func buildEventKey(e *tt.Event) string {
+	return fmt.Sprintf("%s,%s,%s,%s,%s",
+		e.Chromosome,
+		e.StartPosition,
+		e.EndPosition,
+		e.ReferenceAllele,
+		resolveTumorSeqAlleleFromInput(e.ReferenceAllele, e.TumorSeqAllele1, e.TumorSeqAllele2),
+	)
+}
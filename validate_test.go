@@ -0,0 +1,61 @@
+package genome_nexus_annotator_go
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	tt "github.mskcc.org/cdsi/cdsi-protobuf/tempo/generated/v3/go"
+)
+
+func TestValidateEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *tt.Event
+		valid bool
+	}{
+		{"snp", newTestEvent("1", "45797228", "45797228", "C", "T"), true},
+		{"chr prefix", newTestEvent("chrX", "100", "100", "c", "t"), true},
+		{"deletion", newTestEvent("17", "100", "102", "ACG", "-"), true},
+		{"insertion", newTestEvent("9", "21974794", "21974795", "-", "GGC"), true},
+		{"dnp", newTestEvent("7", "140453136", "140453137", "CA", "TT"), true},
+		{"empty chromosome", newTestEvent("", "100", "100", "A", "G"), false},
+		{"unplaced contig", newTestEvent("GL000220.1", "100", "100", "A", "G"), true},
+		{"malformed chromosome", newTestEvent("chr 1", "100", "100", "A", "G"), false},
+		{"chromosome with a comma", newTestEvent("1,2", "100", "100", "A", "G"), false},
+		{"non-numeric start", newTestEvent("1", "abc", "100", "A", "G"), false},
+		{"start out of range", newTestEvent("1", "3000000000", "3000000000", "A", "G"), false},
+		{"start after end", newTestEvent("1", "101", "100", "A", "G"), false},
+		{"empty reference", newTestEvent("1", "100", "100", "", "G"), false},
+		{"empty tumor alleles", newTestEvent("1", "100", "100", "A", ""), false},
+		{"bad allele", newTestEvent("1", "100", "100", "A", "Z"), false},
+		{"snp length", newTestEvent("1", "100", "101", "A", "G"), false},
+		{"deletion length", newTestEvent("1", "100", "100", "ACG", "-"), false},
+		{"insertion length", newTestEvent("1", "100", "100", "-", "G"), false},
+	}
+	for _, test := range tests {
+		err := validateEvent(test.event)
+		if test.valid && err != nil {
+			t.Errorf("%s: expected a valid event but got %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected a validation error", test.name)
+		}
+	}
+}
+
+func TestValidateFixtureEvents(t *testing.T) {
+	b, err := os.ReadFile("testdata/validate_events.json")
+	if err != nil {
+		t.Fatalf("failed to read the events: %v", err)
+	}
+	var events []*tt.Event
+	if err := json.Unmarshal(b, &events); err != nil {
+		t.Fatalf("failed to decode the events: %v", err)
+	}
+	for _, event := range events {
+		if err := validateEvent(event); err != nil {
+			t.Errorf("event %s failed validation: %v", buildEventKey(event), err)
+		}
+	}
+}